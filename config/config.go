@@ -0,0 +1,897 @@
+// Package config loads the settings of the apps into a typed struct, validated at startup.
+//
+// The values are read, from the lowest to the highest precedence, from the defaults,
+// the YAML file set with CONFIG_FILE, the env vars and the .env file
+// (the .env file doesn't override the env vars already set).
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Value shown in place of the secrets by Redacted
+const redacted = "[REDACTED]"
+
+// Config contains the settings of all the apps, each app uses only the ones it needs
+type Config struct {
+	// Host and Port the app listens on, from HOST and PORT
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port" json:"port"`
+
+	// Secondary app called by the main app, from SECONDARY_HOST and SECONDARY_PORT
+	SecondaryHost string `yaml:"secondary_host" json:"secondary_host"`
+	SecondaryPort int    `yaml:"secondary_port" json:"secondary_port"`
+
+	// gRPC server called by the main app, from GRPC_TARGET and GRPC_PORT
+	GRPCTarget string `yaml:"grpc_target" json:"grpc_target"`
+	GRPCPort   int    `yaml:"grpc_port" json:"grpc_port"`
+	// GRPCTimeout is the deadline of the calls to the gRPC server, from GRPC_TIMEOUT
+	GRPCTimeout time.Duration `yaml:"grpc_timeout" json:"grpc_timeout"`
+	// GRPCMaxDeadline caps the deadline of the RPCs served by the gRPC server, from GRPC_MAX_DEADLINE
+	GRPCMaxDeadline time.Duration `yaml:"grpc_max_deadline" json:"grpc_max_deadline"`
+	// GatewayPort is the port of the REST/JSON gateway of the gRPC server, from GATEWAY_PORT
+	GatewayPort int `yaml:"gateway_port" json:"gateway_port"`
+
+	// ExternalURL is the public API called by the apps, from EXTERNAL_URL
+	ExternalURL string `yaml:"external_url" json:"external_url"`
+
+	HTTPClient HTTPClient `yaml:"http_client" json:"http_client"`
+
+	Resilience Resilience `yaml:"resilience" json:"resilience"`
+
+	FakeUpstream FakeUpstream `yaml:"fake_upstream" json:"fake_upstream"`
+
+	OTLPReceiver OTLPReceiver `yaml:"otlp_receiver" json:"otlp_receiver"`
+
+	TraceCheck TraceCheck `yaml:"trace_check" json:"trace_check"`
+
+	LoadTest LoadTest `yaml:"load_test" json:"load_test"`
+
+	Telemetry Telemetry `yaml:"telemetry" json:"telemetry"`
+
+	// sections loaded with WithSections, the other ones are left out of the JSON
+	sections []Section
+}
+
+// HTTPClient sets the time limits of the requests made by the shared HTTP client
+type HTTPClient struct {
+	// Timeout of the requests to the hosts not in HostTimeouts, from HTTP_CLIENT_TIMEOUT
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// HostTimeouts by host name, from HTTP_CLIENT_HOST_TIMEOUTS i.e. "pokeapi.co=5s,secondary-app=2s"
+	HostTimeouts map[string]time.Duration `yaml:"host_timeouts" json:"host_timeouts"`
+}
+
+// Resilience sets the retries and the circuit breakers of the outbound HTTP and gRPC calls
+type Resilience struct {
+	// MaxAttempts of each call, from RETRY_MAX_ATTEMPTS, 1 disables the retries
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+	// InitialBackoff and MaxBackoff between the attempts, from RETRY_INITIAL_BACKOFF and RETRY_MAX_BACKOFF
+	InitialBackoff time.Duration `yaml:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" json:"max_backoff"`
+	// FailureThreshold opening the breaker of an upstream, from CIRCUIT_BREAKER_FAILURE_THRESHOLD,
+	// 0 disables the breakers
+	FailureThreshold int `yaml:"failure_threshold" json:"failure_threshold"`
+	// OpenTimeout before calling again an upstream, from CIRCUIT_BREAKER_OPEN_TIMEOUT
+	OpenTimeout time.Duration `yaml:"open_timeout" json:"open_timeout"`
+}
+
+// FakeUpstream sets the faults injected by the fake-upstream app standing in for pokeapi
+type FakeUpstream struct {
+	// Latency added to each response, from FAKE_UPSTREAM_LATENCY, plus a random
+	// duration up to Jitter, from FAKE_UPSTREAM_JITTER
+	Latency time.Duration `yaml:"latency" json:"latency"`
+	Jitter  time.Duration `yaml:"jitter" json:"jitter"`
+	// ErrorRate is the fraction of the requests failing, from FAKE_UPSTREAM_ERROR_RATE, i.e. 0.1
+	ErrorRate float64 `yaml:"error_rate" json:"error_rate"`
+	// ErrorStatusCodes returned by the failing requests, picked at random,
+	// from FAKE_UPSTREAM_ERROR_STATUS_CODES i.e. "500,503"
+	ErrorStatusCodes []int `yaml:"error_status_codes" json:"error_status_codes"`
+}
+
+// OTLPReceiver sets the otlp-receiver app standing in for the collector
+type OTLPReceiver struct {
+	// GRPCPort and HTTPPort of OTLP, from OTLP_RECEIVER_GRPC_PORT and OTLP_RECEIVER_HTTP_PORT,
+	// the HTTP port serves the trace viewer too
+	GRPCPort int `yaml:"grpc_port" json:"grpc_port"`
+	HTTPPort int `yaml:"http_port" json:"http_port"`
+	// StorageDir keeps the traces on disk, from OTLP_RECEIVER_STORAGE_DIR, in memory when empty
+	StorageDir string `yaml:"storage_dir" json:"storage_dir"`
+	// MaxTraces kept, from OTLP_RECEIVER_MAX_TRACES, the ones not updated for longest are dropped
+	MaxTraces int `yaml:"max_traces" json:"max_traces"`
+}
+
+// TraceCheck sets the trace-check command verifying the traces started by the main app
+type TraceCheck struct {
+	// MainURL of the main app called, from TRACE_CHECK_MAIN_URL
+	MainURL string `yaml:"main_url" json:"main_url"`
+	// ReceiverURL of the otlp-receiver app the spans are exported to, from TRACE_CHECK_RECEIVER_URL
+	ReceiverURL string `yaml:"receiver_url" json:"receiver_url"`
+	// Timeout waiting for the spans of the traces, from TRACE_CHECK_TIMEOUT
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// Names of the services, as set with OTEL_SERVICE_NAME, from TRACE_CHECK_MAIN_SERVICE,
+	// TRACE_CHECK_SECONDARY_SERVICE, TRACE_CHECK_GRPC_SERVICE and TRACE_CHECK_UPSTREAM_SERVICE,
+	// the upstream one is empty when the apps call pokeapi, that is not traced
+	MainService      string `yaml:"main_service" json:"main_service"`
+	SecondaryService string `yaml:"secondary_service" json:"secondary_service"`
+	GRPCService      string `yaml:"grpc_service" json:"grpc_service"`
+	UpstreamService  string `yaml:"upstream_service" json:"upstream_service"`
+}
+
+// LoadTest sets the load-test command generating load on the main app
+type LoadTest struct {
+	// BaseURL of the app called, from LOAD_TEST_BASE_URL
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	// Endpoints called in turn, from LOAD_TEST_ENDPOINTS i.e. "/hello,/hello-grpc-client-stream?count=3"
+	Endpoints []string `yaml:"endpoints" json:"endpoints"`
+	// Stages ramping the virtual users, from LOAD_TEST_STAGES i.e. "10s:20,20s:100,10s:0",
+	// the number of users changes linearly to the target over the duration of each stage
+	Stages []LoadTestStage `yaml:"stages" json:"stages"`
+	// Thresholds failing the run, from LOAD_TEST_THRESHOLDS i.e. "p99<1.5s,error_rate<0.01,/hello-resty:p95<500ms"
+	Thresholds []string `yaml:"thresholds" json:"thresholds"`
+	// RequestTimeout from LOAD_TEST_REQUEST_TIMEOUT
+	RequestTimeout time.Duration `yaml:"request_timeout" json:"request_timeout"`
+	// Slowest is the number of slowest requests reported with their trace, from LOAD_TEST_SLOWEST
+	Slowest int `yaml:"slowest" json:"slowest"`
+	// TraceURL of the trace of a request, {trace_id} is replaced by its ID, from LOAD_TEST_TRACE_URL
+	// i.e. "https://ui.honeycomb.io/<team>/environments/<environment>/trace?trace_id={trace_id}",
+	// only the trace ID is reported when empty
+	TraceURL string `yaml:"trace_url" json:"trace_url"`
+}
+
+// LoadTestStage ramps the virtual users of the load-test command to Target over Duration
+type LoadTestStage struct {
+	Duration time.Duration `yaml:"duration" json:"duration"`
+	Target   int           `yaml:"target" json:"target"`
+}
+
+// Telemetry contains the standard OpenTelemetry env vars, the values set in the
+// YAML file are exported to the env so that the SDK reads them
+type Telemetry struct {
+	ServiceName    string `yaml:"service_name" json:"service_name"`
+	Endpoint       string `yaml:"endpoint" json:"endpoint"`
+	Protocol       string `yaml:"protocol" json:"protocol"`
+	Headers        string `yaml:"headers" json:"headers"`
+	MetricsHeaders string `yaml:"metrics_headers" json:"metrics_headers"`
+}
+
+// Section is a part of the config used by a single app, its env vars are read and validated
+// only by the app loading it with WithSections, so that the other apps ignore them
+type Section int
+
+const (
+	// FakeUpstreamSection is FakeUpstream, used by the fake-upstream app
+	FakeUpstreamSection Section = iota + 1
+	// OTLPReceiverSection is OTLPReceiver, used by the otlp-receiver app
+	OTLPReceiverSection
+	// TraceCheckSection is TraceCheck, used by the trace-check command
+	TraceCheckSection
+	// LoadTestSection is LoadTest, used by the load-test command
+	LoadTestSection
+)
+
+// JSON keys of the sections
+var sectionKeys = map[Section]string{
+	FakeUpstreamSection: "fake_upstream",
+	OTLPReceiverSection: "otlp_receiver",
+	TraceCheckSection:   "trace_check",
+	LoadTestSection:     "load_test",
+}
+
+// section reads and validates the fields of a Section
+type section interface {
+	readEnv() []error
+	validate() error
+}
+
+func (c *Config) section(s Section) section {
+	switch s {
+	case FakeUpstreamSection:
+		return &c.FakeUpstream
+	case OTLPReceiverSection:
+		return &c.OTLPReceiver
+	case TraceCheckSection:
+		return &c.TraceCheck
+	case LoadTestSection:
+		return &c.LoadTest
+	default:
+		panic(fmt.Sprintf("unknown config section %d", s))
+	}
+}
+
+// Option changes the defaults used by Load
+type Option func(*options)
+
+type options struct {
+	defaults Config
+	envFiles []string
+	sections []Section
+}
+
+// WithDefaultPort sets the port used when PORT is not set
+func WithDefaultPort(port int) Option {
+	return func(o *options) {
+		o.defaults.Port = port
+	}
+}
+
+// WithSections reads and validates the sections used by the app, besides the settings shared by all the apps
+func WithSections(sections ...Section) Option {
+	return func(o *options) {
+		o.sections = append(o.sections, sections...)
+	}
+}
+
+// WithEnvFiles replaces the .env file with the files passed, the missing ones are ignored
+func WithEnvFiles(files ...string) Option {
+	return func(o *options) {
+		o.envFiles = files
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		defaults: Config{
+			Host:            "localhost",
+			Port:            8080,
+			SecondaryHost:   "localhost",
+			SecondaryPort:   8082,
+			GRPCTarget:      "localhost",
+			GRPCPort:        7070,
+			GRPCTimeout:     5 * time.Second,
+			GRPCMaxDeadline: 30 * time.Second,
+			GatewayPort:     7071,
+			ExternalURL:     "https://pokeapi.co/api/v2/pokemon/ditto",
+			HTTPClient: HTTPClient{
+				Timeout: 10 * time.Second,
+			},
+			Resilience: Resilience{
+				MaxAttempts:      3,
+				InitialBackoff:   100 * time.Millisecond,
+				MaxBackoff:       2 * time.Second,
+				FailureThreshold: 5,
+				OpenTimeout:      30 * time.Second,
+			},
+			FakeUpstream: FakeUpstream{
+				ErrorStatusCodes: []int{http.StatusInternalServerError, http.StatusServiceUnavailable},
+			},
+			OTLPReceiver: OTLPReceiver{
+				GRPCPort:  4317,
+				HTTPPort:  4318,
+				MaxTraces: 1000,
+			},
+			TraceCheck: TraceCheck{
+				MainURL:          "http://localhost:8080",
+				ReceiverURL:      "http://localhost:4318",
+				Timeout:          30 * time.Second,
+				MainService:      "GoFiberExample",
+				SecondaryService: "SecondaryExample",
+				GRPCService:      "gRPCServerExample",
+				UpstreamService:  "FakeUpstreamExample",
+			},
+			LoadTest: LoadTest{
+				BaseURL: "http://localhost:8080",
+				Endpoints: []string{
+					"/hello",
+					"/hello-child",
+					"/hello-otelhttp",
+					"/hello-http-client",
+					"/hello-resty",
+					"/hello-grpc",
+					"/hello-grpc-server-stream?count=3&interval_ms=10",
+					"/hello-grpc-client-stream?count=3",
+					"/hello-grpc-bidi-stream?count=3",
+				},
+				// the stages of the k6 script
+				Stages: []LoadTestStage{
+					{Duration: 10 * time.Second, Target: 20},
+					{Duration: 20 * time.Second, Target: 100},
+					{Duration: 10 * time.Second, Target: 0},
+				},
+				Thresholds:     []string{"p99<1.5s", "error_rate<0.01"},
+				RequestTimeout: 10 * time.Second,
+				Slowest:        10,
+				TraceURL:       "http://localhost:4318/traces/{trace_id}",
+			},
+		},
+		envFiles: []string{".env"},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Load reads and validates the config, all the invalid values are reported together
+func Load(opts ...Option) (*Config, error) {
+	o := newOptions(opts...)
+
+	for _, file := range o.envFiles {
+		if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load %s: %w", file, err)
+		}
+	}
+
+	cfg := o.defaults
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	errs := cfg.readEnv()
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, s := range o.sections {
+		sec := cfg.section(s)
+		errs = append(errs, sec.readEnv()...)
+		errs = append(errs, sec.validate())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if err := cfg.Telemetry.exportEnv(); err != nil {
+		return nil, err
+	}
+
+	cfg.sections = o.sections
+
+	return &cfg, nil
+}
+
+// Address the app listens on
+func (c *Config) Address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// SecondaryAddress is the host and port of the secondary app
+func (c *Config) SecondaryAddress() string {
+	return net.JoinHostPort(c.SecondaryHost, strconv.Itoa(c.SecondaryPort))
+}
+
+// GRPCAddress is the host and port of the gRPC server
+func (c *Config) GRPCAddress() string {
+	return net.JoinHostPort(c.GRPCTarget, strconv.Itoa(c.GRPCPort))
+}
+
+// GatewayAddress is the host and port the gateway of the gRPC server listens on
+func (c *Config) GatewayAddress() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.GatewayPort))
+}
+
+// OTLPReceiverGRPCAddress is the host and port the otlp-receiver app receives OTLP over gRPC on
+func (c *Config) OTLPReceiverGRPCAddress() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.OTLPReceiver.GRPCPort))
+}
+
+// OTLPReceiverHTTPAddress is the host and port the otlp-receiver app receives OTLP over HTTP
+// and serves the trace viewer on
+func (c *Config) OTLPReceiverHTTPAddress() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.OTLPReceiver.HTTPPort))
+}
+
+// Validate checks the hosts, ports and URLs shared by all the apps, the sections are validated by Load
+func (c *Config) Validate() error {
+	return errors.Join(
+		validateHost("HOST", c.Host),
+		validatePort("PORT", c.Port),
+		validateHost("SECONDARY_HOST", c.SecondaryHost),
+		validatePort("SECONDARY_PORT", c.SecondaryPort),
+		validateHost("GRPC_TARGET", c.GRPCTarget),
+		validatePort("GRPC_PORT", c.GRPCPort),
+		validateDuration("GRPC_TIMEOUT", c.GRPCTimeout),
+		validateDuration("GRPC_MAX_DEADLINE", c.GRPCMaxDeadline),
+		validatePort("GATEWAY_PORT", c.GatewayPort),
+		validateURL("EXTERNAL_URL", c.ExternalURL, true),
+		validateOTLPEndpoint("OTEL_EXPORTER_OTLP_ENDPOINT", c.Telemetry.Endpoint),
+		c.HTTPClient.validate(),
+		c.Resilience.validate(),
+	)
+}
+
+func (l *LoadTest) readEnv() []error {
+	lookupString("LOAD_TEST_BASE_URL", &l.BaseURL)
+	lookupStrings("LOAD_TEST_ENDPOINTS", &l.Endpoints)
+	lookupStrings("LOAD_TEST_THRESHOLDS", &l.Thresholds)
+	lookupString("LOAD_TEST_TRACE_URL", &l.TraceURL)
+	return []error{
+		lookupStages("LOAD_TEST_STAGES", &l.Stages),
+		lookupDuration("LOAD_TEST_REQUEST_TIMEOUT", &l.RequestTimeout),
+		lookupInt("LOAD_TEST_SLOWEST", &l.Slowest),
+	}
+}
+
+// the thresholds are parsed by the load-test command
+func (l *LoadTest) validate() error {
+	var errs []error
+	if len(l.Endpoints) == 0 {
+		errs = append(errs, errors.New("LOAD_TEST_ENDPOINTS is empty"))
+	}
+	for _, endpoint := range l.Endpoints {
+		if !strings.HasPrefix(endpoint, "/") {
+			errs = append(errs, fmt.Errorf("LOAD_TEST_ENDPOINTS %q must be a path starting with /", endpoint))
+		}
+	}
+	var total time.Duration
+	for _, stage := range l.Stages {
+		if stage.Duration < 0 {
+			errs = append(errs, fmt.Errorf("LOAD_TEST_STAGES duration %s must not be negative", stage.Duration))
+		}
+		if stage.Target < 0 {
+			errs = append(errs, fmt.Errorf("LOAD_TEST_STAGES target %d must not be negative", stage.Target))
+		}
+		total += stage.Duration
+	}
+	if total <= 0 {
+		errs = append(errs, errors.New("LOAD_TEST_STAGES must last more than 0s"))
+	}
+	if l.Slowest < 0 {
+		errs = append(errs, fmt.Errorf("LOAD_TEST_SLOWEST %d must not be negative", l.Slowest))
+	}
+	if l.TraceURL != "" && !strings.Contains(l.TraceURL, "{trace_id}") {
+		errs = append(errs, fmt.Errorf("LOAD_TEST_TRACE_URL %q must contain {trace_id}", l.TraceURL))
+	}
+	errs = append(errs,
+		validateURL("LOAD_TEST_BASE_URL", l.BaseURL, true),
+		validateDuration("LOAD_TEST_REQUEST_TIMEOUT", l.RequestTimeout),
+	)
+	return errors.Join(errs...)
+}
+
+func (t *TraceCheck) readEnv() []error {
+	lookupString("TRACE_CHECK_MAIN_URL", &t.MainURL)
+	lookupString("TRACE_CHECK_RECEIVER_URL", &t.ReceiverURL)
+	lookupString("TRACE_CHECK_MAIN_SERVICE", &t.MainService)
+	lookupString("TRACE_CHECK_SECONDARY_SERVICE", &t.SecondaryService)
+	lookupString("TRACE_CHECK_GRPC_SERVICE", &t.GRPCService)
+	lookupString("TRACE_CHECK_UPSTREAM_SERVICE", &t.UpstreamService)
+	return []error{lookupDuration("TRACE_CHECK_TIMEOUT", &t.Timeout)}
+}
+
+func (t *TraceCheck) validate() error {
+	var errs []error
+	for _, service := range []struct{ env, name string }{
+		{"TRACE_CHECK_MAIN_SERVICE", t.MainService},
+		{"TRACE_CHECK_SECONDARY_SERVICE", t.SecondaryService},
+		{"TRACE_CHECK_GRPC_SERVICE", t.GRPCService},
+	} {
+		if service.name == "" {
+			errs = append(errs, fmt.Errorf("%s is empty", service.env))
+		}
+	}
+	errs = append(errs,
+		validateURL("TRACE_CHECK_MAIN_URL", t.MainURL, true),
+		validateURL("TRACE_CHECK_RECEIVER_URL", t.ReceiverURL, true),
+		validateDuration("TRACE_CHECK_TIMEOUT", t.Timeout),
+	)
+	return errors.Join(errs...)
+}
+
+func (o *OTLPReceiver) readEnv() []error {
+	lookupString("OTLP_RECEIVER_STORAGE_DIR", &o.StorageDir)
+	return []error{
+		lookupInt("OTLP_RECEIVER_GRPC_PORT", &o.GRPCPort),
+		lookupInt("OTLP_RECEIVER_HTTP_PORT", &o.HTTPPort),
+		lookupInt("OTLP_RECEIVER_MAX_TRACES", &o.MaxTraces),
+	}
+}
+
+func (o *OTLPReceiver) validate() error {
+	var errs []error
+	if o.MaxTraces < 1 {
+		errs = append(errs, fmt.Errorf("OTLP_RECEIVER_MAX_TRACES %d must be at least 1", o.MaxTraces))
+	}
+	errs = append(errs,
+		validatePort("OTLP_RECEIVER_GRPC_PORT", o.GRPCPort),
+		validatePort("OTLP_RECEIVER_HTTP_PORT", o.HTTPPort),
+	)
+	return errors.Join(errs...)
+}
+
+func (f *FakeUpstream) readEnv() []error {
+	return []error{
+		lookupDuration("FAKE_UPSTREAM_LATENCY", &f.Latency),
+		lookupDuration("FAKE_UPSTREAM_JITTER", &f.Jitter),
+		lookupFloat("FAKE_UPSTREAM_ERROR_RATE", &f.ErrorRate),
+		lookupInts("FAKE_UPSTREAM_ERROR_STATUS_CODES", &f.ErrorStatusCodes),
+	}
+}
+
+func (f *FakeUpstream) validate() error {
+	var errs []error
+	if f.Latency < 0 {
+		errs = append(errs, fmt.Errorf("FAKE_UPSTREAM_LATENCY %s must not be negative", f.Latency))
+	}
+	if f.Jitter < 0 {
+		errs = append(errs, fmt.Errorf("FAKE_UPSTREAM_JITTER %s must not be negative", f.Jitter))
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		errs = append(errs, fmt.Errorf("FAKE_UPSTREAM_ERROR_RATE %v must be between 0 and 1", f.ErrorRate))
+	}
+	if f.ErrorRate > 0 && len(f.ErrorStatusCodes) == 0 {
+		errs = append(errs, errors.New("FAKE_UPSTREAM_ERROR_STATUS_CODES is empty"))
+	}
+	for _, code := range f.ErrorStatusCodes {
+		if code < 400 || code > 599 {
+			errs = append(errs, fmt.Errorf("FAKE_UPSTREAM_ERROR_STATUS_CODES %d must be an error status code", code))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Resilience) validate() error {
+	var errs []error
+	if r.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("RETRY_MAX_ATTEMPTS %d must be at least 1", r.MaxAttempts))
+	}
+	if r.FailureThreshold < 0 {
+		errs = append(errs, fmt.Errorf("CIRCUIT_BREAKER_FAILURE_THRESHOLD %d must not be negative", r.FailureThreshold))
+	}
+	errs = append(errs,
+		validateDuration("RETRY_INITIAL_BACKOFF", r.InitialBackoff),
+		validateDuration("RETRY_MAX_BACKOFF", r.MaxBackoff),
+		validateDuration("CIRCUIT_BREAKER_OPEN_TIMEOUT", r.OpenTimeout),
+	)
+	return errors.Join(errs...)
+}
+
+func (h *HTTPClient) validate() error {
+	errs := []error{validateDuration("HTTP_CLIENT_TIMEOUT", h.Timeout)}
+	for host, timeout := range h.HostTimeouts {
+		errs = append(errs,
+			validateHost("HTTP_CLIENT_HOST_TIMEOUTS", host),
+			validateDuration("HTTP_CLIENT_HOST_TIMEOUTS "+host, timeout),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy safe to be logged or served, with the secrets replaced
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.ExternalURL = redactURL(r.ExternalURL)
+	r.Telemetry.Endpoint = redactURL(r.Telemetry.Endpoint)
+	if r.Telemetry.Headers != "" {
+		r.Telemetry.Headers = redacted
+	}
+	if r.Telemetry.MetricsHeaders != "" {
+		r.Telemetry.MetricsHeaders = redacted
+	}
+	return &r
+}
+
+// MarshalJSON outputs the shared settings and only the sections loaded by the app,
+// with the durations as strings i.e. "2s"
+func (c Config) MarshalJSON() ([]byte, error) {
+	fields := jsonValue(reflect.ValueOf(c)).(map[string]any)
+	for s, key := range sectionKeys {
+		if !slices.Contains(c.sections, s) {
+			delete(fields, key)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// jsonValue converts the structs to maps keyed by their JSON names and the durations to strings
+func jsonValue(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" {
+				name = f.Name
+			}
+			fields[name] = jsonValue(v.Field(i))
+		}
+		return fields
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		values := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			values[fmt.Sprint(it.Key().Interface())] = jsonValue(it.Value())
+		}
+		return values
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = jsonValue(v.Index(i))
+		}
+		return values
+	default:
+		return v.Interface()
+	}
+}
+
+// readEnv overrides the fields with the env vars set, returning the values that can't be parsed
+func (c *Config) readEnv() []error {
+	var errs []error
+
+	lookupString("HOST", &c.Host)
+	errs = append(errs, lookupInt("PORT", &c.Port))
+	lookupString("SECONDARY_HOST", &c.SecondaryHost)
+	errs = append(errs, lookupInt("SECONDARY_PORT", &c.SecondaryPort))
+	lookupString("GRPC_TARGET", &c.GRPCTarget)
+	errs = append(errs, lookupInt("GRPC_PORT", &c.GRPCPort))
+	errs = append(errs, lookupInt("GATEWAY_PORT", &c.GatewayPort))
+	lookupString("EXTERNAL_URL", &c.ExternalURL)
+	errs = append(errs,
+		lookupDuration("GRPC_TIMEOUT", &c.GRPCTimeout),
+		lookupDuration("GRPC_MAX_DEADLINE", &c.GRPCMaxDeadline),
+		lookupDuration("HTTP_CLIENT_TIMEOUT", &c.HTTPClient.Timeout),
+		lookupDurations("HTTP_CLIENT_HOST_TIMEOUTS", &c.HTTPClient.HostTimeouts),
+		lookupInt("RETRY_MAX_ATTEMPTS", &c.Resilience.MaxAttempts),
+		lookupDuration("RETRY_INITIAL_BACKOFF", &c.Resilience.InitialBackoff),
+		lookupDuration("RETRY_MAX_BACKOFF", &c.Resilience.MaxBackoff),
+		lookupInt("CIRCUIT_BREAKER_FAILURE_THRESHOLD", &c.Resilience.FailureThreshold),
+		lookupDuration("CIRCUIT_BREAKER_OPEN_TIMEOUT", &c.Resilience.OpenTimeout),
+	)
+
+	for env, value := range c.Telemetry.env() {
+		lookupString(env, value)
+	}
+
+	return errs
+}
+
+// env maps the OpenTelemetry env vars to the fields
+func (t *Telemetry) env() map[string]*string {
+	return map[string]*string{
+		"OTEL_SERVICE_NAME":                  &t.ServiceName,
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        &t.Endpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL":        &t.Protocol,
+		"OTEL_EXPORTER_OTLP_HEADERS":         &t.Headers,
+		"OTEL_EXPORTER_OTLP_METRICS_HEADERS": &t.MetricsHeaders,
+	}
+}
+
+// exportEnv sets the env vars of the values coming from the YAML file
+func (t *Telemetry) exportEnv() error {
+	for env, value := range t.env() {
+		if *value == "" || os.Getenv(env) != "" {
+			continue
+		}
+		if err := os.Setenv(env, *value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+func lookupString(env string, value *string) {
+	if v, ok := os.LookupEnv(env); ok {
+		*value = v
+	}
+}
+
+// lookupStrings parses a comma separated list, i.e. "/hello,/hello-grpc"
+func lookupStrings(env string, value *[]string) {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return
+	}
+	var values []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	*value = values
+}
+
+func lookupInt(env string, value *int) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s %q is not a number", env, v)
+	}
+	*value = n
+	return nil
+}
+
+func lookupFloat(env string, value *float64) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return fmt.Errorf("%s %q is not a number", env, v)
+	}
+	*value = f
+	return nil
+}
+
+// lookupInts parses a comma separated list of numbers, i.e. "500,503"
+func lookupInts(env string, value *[]int) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	var numbers []int
+	for _, s := range strings.Split(v, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("%s %q must be a list of numbers", env, v)
+		}
+		numbers = append(numbers, n)
+	}
+	*value = numbers
+	return nil
+}
+
+func lookupDuration(env string, value *time.Duration) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s %q is not a duration", env, v)
+	}
+	*value = d
+	return nil
+}
+
+// lookupDurations parses a list of host=duration pairs, i.e. "pokeapi.co=5s,secondary-app=2s"
+func lookupDurations(env string, value *map[string]time.Duration) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	durations := map[string]time.Duration{}
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, duration, found := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if !found || err != nil {
+			return fmt.Errorf("%s %q must be a list of host=duration", env, v)
+		}
+		durations[strings.TrimSpace(key)] = d
+	}
+	*value = durations
+	return nil
+}
+
+// lookupStages parses a list of duration:target pairs, i.e. "10s:20,20s:100,10s:0"
+func lookupStages(env string, value *[]LoadTestStage) error {
+	v, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	var stages []LoadTestStage
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		duration, target, found := strings.Cut(pair, ":")
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if !found || err != nil {
+			return fmt.Errorf("%s %q must be a list of duration:target", env, v)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(target))
+		if err != nil {
+			return fmt.Errorf("%s %q must be a list of duration:target", env, v)
+		}
+		stages = append(stages, LoadTestStage{Duration: d, Target: n})
+	}
+	*value = stages
+	return nil
+}
+
+func validateHost(name, host string) error {
+	if host == "" {
+		return fmt.Errorf("%s is empty", name)
+	}
+	// IP addresses, i.e. ::1 for IPv6, without brackets
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	if u, err := url.Parse("//" + host); err != nil || u.Host != host || u.Port() != "" || strings.HasPrefix(host, "[") {
+		return fmt.Errorf("%s %q must be a host name or an IP address without scheme and port", name, host)
+	}
+	return nil
+}
+
+func validatePort(name string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s %d must be between 1 and 65535", name, port)
+	}
+	return nil
+}
+
+func validateDuration(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s %s must be positive", name, d)
+	}
+	return nil
+}
+
+func validateURL(name, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s is empty", name)
+		}
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid URL: %w", name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s %q must be an http or https URL", name, value)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s %q has no host", name, value)
+	}
+	return nil
+}
+
+// validateOTLPEndpoint accepts an http or https URL or, like the gRPC exporter, a host:port without scheme
+func validateOTLPEndpoint(name, value string) error {
+	if value == "" || strings.Contains(value, "://") {
+		return validateURL(name, value, false)
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("%s %q must be an http or https URL or a host:port", name, value)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%s %q must have a numeric port", name, value)
+	}
+	return errors.Join(validateHost(name, host), validatePort(name, n))
+}
+
+// redactURL hides the password of the URLs with credentials
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+	return u.Redacted()
+}
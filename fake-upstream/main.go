@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/fakeupstream"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+)
+
+func main() {
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
+
+	cfg, err := config.Load(config.WithDefaultPort(8090), config.WithSections(config.FakeUpstreamSection))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app, err := server.NewFiberApp(ctx, server.WithAddress(cfg.Address()))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// i.e. GET /api/v2/pokemon/ditto, with the faults set with the FAKE_UPSTREAM_* env vars
+	fakeupstream.Register(app,
+		fakeupstream.WithLatency(cfg.FakeUpstream.Latency, cfg.FakeUpstream.Jitter),
+		fakeupstream.WithErrorRate(cfg.FakeUpstream.ErrorRate, cfg.FakeUpstream.ErrorStatusCodes...),
+	)
+
+	if err := app.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
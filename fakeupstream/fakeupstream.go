@@ -0,0 +1,171 @@
+// Package fakeupstream stands in for the pokeapi endpoint called by the apps, serving the
+// embedded fixtures with the latency and the errors configured, so that the demos work offline.
+// It runs as the fake-upstream app or in-process, i.e. in the tests, with NewServer.
+package fakeupstream
+
+import (
+	"embed"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/otelfiber"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PokemonPath is the route of the pokeapi endpoint, the fixtures are named after the pokemon
+const PokemonPath = "/api/v2/pokemon/:name"
+
+// Names of the span event and attributes
+const (
+	faultEvent        = "fake_upstream.fault"
+	disconnectedEvent = "fake_upstream.client_disconnected"
+
+	latencyKey    = attribute.Key("fake_upstream.latency")
+	statusCodeKey = attribute.Key("fake_upstream.status_code")
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Option changes the faults injected
+type Option func(*options)
+
+type options struct {
+	latency          time.Duration
+	jitter           time.Duration
+	errorRate        float64
+	errorStatusCodes []int
+}
+
+// WithLatency delays each response by latency plus a random duration up to jitter
+func WithLatency(latency, jitter time.Duration) Option {
+	return func(o *options) {
+		o.latency = latency
+		o.jitter = jitter
+	}
+}
+
+// WithErrorRate makes the fraction rate of the requests, i.e. 0.1, fail with one of the
+// status codes picked at random, 500 and 503 when none is passed
+func WithErrorRate(rate float64, statusCodes ...int) Option {
+	return func(o *options) {
+		o.errorRate = rate
+		if len(statusCodes) > 0 {
+			o.errorStatusCodes = statusCodes
+		}
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		errorStatusCodes: []int{http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Register adds the pokeapi route to the router, i.e. the app created with server.NewFiberApp
+func Register(router fiber.Router, opts ...Option) {
+	h := &handler{options: newOptions(opts...)}
+	router.Get(PokemonPath, h.pokemon)
+}
+
+// NewServer starts the fake upstream in-process, traced with the global TracerProvider,
+// it must be closed when done
+func NewServer(opts ...Option) *httptest.Server {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(otelfiber.Middleware())
+	Register(app, opts...)
+	return httptest.NewServer(adaptor.FiberApp(app))
+}
+
+// DittoURL is the URL of the ditto fixture on the server at baseURL, i.e. the URL of NewServer
+func DittoURL(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/api/v2/pokemon/ditto"
+}
+
+type handler struct {
+	*options
+}
+
+func (h *handler) pokemon(c *fiber.Ctx) error {
+	span := trace.SpanFromContext(c.UserContext())
+
+	if delay := h.delay(); delay > 0 {
+		span.SetAttributes(latencyKey.String(delay.String()))
+		disconnected, stopWatching := watchDisconnect(c)
+		select {
+		case <-time.After(delay):
+		case <-disconnected:
+			// i.e. the client timed out, nobody reads the response
+			span.AddEvent(disconnectedEvent)
+			return nil
+		case <-c.Context().Done():
+		}
+		stopWatching()
+	}
+
+	if h.errorRate > 0 && rand.Float64() < h.errorRate {
+		code := h.errorStatusCodes[rand.N(len(h.errorStatusCodes))]
+		span.AddEvent(faultEvent, trace.WithAttributes(statusCodeKey.Int(code)))
+		return c.Status(code).JSON(fiber.Map{"error": http.StatusText(code)})
+	}
+
+	// like pokeapi, the names not found get a plain text 404
+	fixture, err := fixtures.ReadFile(path.Join("fixtures", path.Base(c.Params("name"))+".json"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("Not Found")
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(fixture)
+}
+
+// watchDisconnect returns a channel closed when the client closes the connection while the
+// response is delayed, and the func to call to stop watching before writing the response.
+// The client doesn't send anything else on the connection until it gets the response, so the
+// read returns only when the connection is closed. The connections that are not TCP, i.e. the
+// one of the net/http adaptor of NewServer, are not watched.
+func watchDisconnect(c *fiber.Ctx) (<-chan struct{}, func()) {
+	disconnected := make(chan struct{})
+	conn, ok := c.Context().Conn().(*net.TCPConn)
+	if !ok {
+		return disconnected, func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var b [1]byte
+		if _, err := conn.Read(b[:]); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			close(disconnected)
+		}
+	}()
+
+	return disconnected, func() {
+		// unblocks the read, then restores the deadline for the next request
+		_ = conn.SetReadDeadline(time.Now())
+		<-done
+		_ = conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// delay returns the latency of a response
+func (h *handler) delay() time.Duration {
+	if h.jitter <= 0 {
+		return h.latency
+	}
+	return h.latency + rand.N(h.jitter)
+}
@@ -2,24 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"net"
-	"os"
+	"log/slog"
+	"strings"
+	"time"
 
-	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/grpcmiddleware"
 	"github.com/emanuelef/go-fiber-honeycomb/proto"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
 
-	_ "github.com/joho/godotenv/autoload"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
+// Defaults of SayHelloServerStream
+const (
+	defaultStreamCount    = 5
+	defaultStreamInterval = 200 * time.Millisecond
+)
+
 var tracer trace.Tracer
 
 func init() {
@@ -27,63 +35,142 @@ func init() {
 	tracer = otel.Tracer("github.com/emanuelef/go-fiber-honeycomb/grpc-server")
 }
 
-func getEnv(key, fallback string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		value = fallback
-	}
-	return value
-}
-
-// server is used to implement helloworld.GreeterServer.
-type server struct {
+// greeterServer is used to implement helloworld.GreeterServer.
+type greeterServer struct {
 	protos.UnimplementedGreeterServer
 }
 
 // SayHello implements helloworld.GreeterServer
-func (s *server) SayHello(ctx context.Context, in *protos.HelloRequest) (*protos.HelloResponse, error) {
-	log.Printf("Received: %v", in.GetGreeting())
+func (s *greeterServer) SayHello(ctx context.Context, in *protos.HelloRequest) (*protos.HelloResponse, error) {
+	slog.InfoContext(ctx, "Received greeting", slog.String("greeting", in.GetGreeting()))
 
 	_, childSpan := tracer.Start(ctx, "SayHelloCustom")
 	defer childSpan.End()
 
-	if in.Greeting == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "request missing required field: Greeting")
+	// the greeting is required, checked by the validation interceptor with the rules in simple.proto
+	return &protos.HelloResponse{Reply: "Hello " + in.GetGreeting()}, nil
+}
+
+// SayHelloServerStream sends a reply every interval, each one recorded as an event of the RPC span
+func (s *greeterServer) SayHelloServerStream(in *protos.HelloStreamRequest, stream grpc.ServerStreamingServer[protos.HelloResponse]) error {
+	ctx := stream.Context()
+	span := trace.SpanFromContext(ctx)
+
+	// the greeting is required and the count at most 100, see the rules in simple.proto
+	count := int(in.GetCount())
+	if count <= 0 {
+		count = defaultStreamCount
 	}
 
-	return &protos.HelloResponse{Reply: "Hello " + in.GetGreeting()}, nil
+	interval := time.Duration(in.GetIntervalMs()) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultStreamInterval
+	}
+
+	for i := range count {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return status.FromContextError(ctx.Err()).Err()
+			case <-time.After(interval):
+			}
+		}
+
+		reply := &protos.HelloResponse{Reply: fmt.Sprintf("Hello %s #%d", in.GetGreeting(), i+1)}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+		span.AddEvent("reply sent", trace.WithAttributes(
+			attribute.Int("message.index", i),
+			attribute.String("reply", reply.GetReply()),
+		))
+	}
+
+	return nil
 }
 
-func main() {
-	ctx := context.Background()
-	tp, exp, _ := otel_instrumentation.InitializeGlobalTracerProvider(ctx)
+// SayHelloClientStream replies to all the greetings once the client closes the stream
+func (s *greeterServer) SayHelloClientStream(stream grpc.ClientStreamingServer[protos.HelloRequest, protos.HelloSummary]) error {
+	span := trace.SpanFromContext(stream.Context())
+
+	var greetings []string
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		span.AddEvent("greeting received", trace.WithAttributes(
+			attribute.Int("message.index", len(greetings)),
+			attribute.String("greeting", in.GetGreeting()),
+		))
+		greetings = append(greetings, in.GetGreeting())
+	}
+
+	slog.InfoContext(stream.Context(), "Received greetings", slog.Int("count", len(greetings)))
+
+	return stream.SendAndClose(&protos.HelloSummary{
+		Count: int32(len(greetings)),
+		Reply: "Hello " + strings.Join(greetings, ", "),
+	})
+}
 
-	// Handle shutdown to ensure all sub processes are closed correctly and telemetry is exported
-	defer func() {
-		_ = exp.Shutdown(ctx)
-		_ = tp.Shutdown(ctx)
-	}()
+// SayHelloBidiStream replies to each greeting as soon as it is received
+func (s *greeterServer) SayHelloBidiStream(stream grpc.BidiStreamingServer[protos.HelloRequest, protos.HelloResponse]) error {
+	span := trace.SpanFromContext(stream.Context())
+
+	for i := 0; ; i++ {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		span.AddEvent("greeting received", trace.WithAttributes(
+			attribute.Int("message.index", i),
+			attribute.String("greeting", in.GetGreeting()),
+		))
+
+		reply := &protos.HelloResponse{Reply: "Hello " + in.GetGreeting()}
+		if err := stream.Send(reply); err != nil {
+			return err
+		}
+		span.AddEvent("reply sent", trace.WithAttributes(
+			attribute.Int("message.index", i),
+			attribute.String("reply", reply.GetReply()),
+		))
+	}
+}
 
-	host := getEnv("HOST", "localhost")
-	port := getEnv("PORT", "7070")
-	hostAddress := fmt.Sprintf("%s:%s", host, port)
+func main() {
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
 
-	lis, err := net.Listen("tcp", hostAddress)
+	cfg, err := config.Load(config.WithDefaultPort(7070))
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		log.Fatal(err)
 	}
-	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
 
-	// Register reflection service on gRPC server.
-	reflection.Register(grpcServer)
+	grpcServer, err := server.NewGRPCServer(ctx,
+		server.WithAddress(cfg.Address()),
+		server.WithInterceptorOptions(grpcmiddleware.WithMaxDeadline(cfg.GRPCMaxDeadline)),
+		// REST/JSON transcoding of the Greeter service, see the HTTP rules in simple.proto
+		server.WithGateway(cfg.GatewayAddress(), protos.RegisterGreeterHandler),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Register the server
-	protos.RegisterGreeterServer(grpcServer, &server{})
-
-	log.Printf("Starting server on address %s", lis.Addr().String())
+	protos.RegisterGreeterServer(grpcServer, &greeterServer{})
 
 	// Start listening
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to listen: %v", err)
+	if err := grpcServer.Run(ctx); err != nil {
+		log.Fatal(err)
 	}
 }
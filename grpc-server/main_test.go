@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	protos "github.com/emanuelef/go-fiber-honeycomb/proto"
+	"github.com/emanuelef/go-fiber-honeycomb/telemetrytest"
+)
+
+func TestSayHello(t *testing.T) {
+	rec := telemetrytest.NewRecorder(t)
+	client := telemetrytest.StartGreeter(t, &greeterServer{})
+
+	r, err := client.SayHello(context.Background(), &protos.HelloRequest{Greeting: "ciao"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.GetReply() != "Hello ciao" {
+		t.Fatalf("reply %q, want %q", r.GetReply(), "Hello ciao")
+	}
+
+	server := rec.RequireServerSpan(t, "protos.Greeter/SayHello")
+	rec.RequireChild(t, server, "SayHelloCustom")
+}
@@ -0,0 +1,258 @@
+// Package grpcclient creates the long-lived Greeter client shared by the handlers,
+// the channel is connected on the first call and reconnected when the server is not healthy
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	protos "github.com/emanuelef/go-fiber-honeycomb/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+
+	// registers the client side health checking used by the service config
+	_ "google.golang.org/grpc/health"
+)
+
+const meterName = "github.com/emanuelef/go-fiber-honeycomb/grpcclient"
+
+// Defaults used when the options are not set
+const (
+	defaultTimeout          = 5 * time.Second
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 10 * time.Second
+)
+
+// The subchannels are checked with the grpc.health.v1 service, a server reporting NOT_SERVING
+// (i.e. while shutting down) is not used until it is healthy again. A server without the
+// health service is considered healthy.
+const serviceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": ""}
+}`
+
+// Option changes a setting of the client created by New
+type Option func(*options)
+
+type options struct {
+	timeout          time.Duration
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+	interceptors     []grpc.UnaryClientInterceptor
+	dialOptions      []grpc.DialOption
+}
+
+// WithTimeout sets the deadline of the calls made with a context without one
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithKeepalive sets how often the idle connection is pinged and how long to wait for the ack,
+// the server must permit pings this frequent, see server.NewGRPCServer
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return func(o *options) {
+		o.keepaliveTime = interval
+		o.keepaliveTimeout = timeout
+	}
+}
+
+// WithUnaryInterceptors adds interceptors run after the deadline is set, i.e. the retries
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithDialOptions adds options to the channel, i.e. the transport credentials
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		timeout:          defaultTimeout,
+		keepaliveTime:    defaultKeepaliveTime,
+		keepaliveTimeout: defaultKeepaliveTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Client is a GreeterClient over a single channel, safe for concurrent use
+type Client struct {
+	protos.GreeterClient
+
+	conn         *grpc.ClientConn
+	target       string
+	stopWatching context.CancelFunc
+	watcherDone  chan struct{}
+
+	mu           sync.Mutex
+	state        connectivity.State
+	transitions  metric.Int64Counter
+	registration metric.Registration
+}
+
+// New creates the client without connecting, to be called once at startup and closed on shutdown
+func New(target string, opts ...Option) (*Client, error) {
+	o := newOptions(opts...)
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		grpc.WithChainUnaryInterceptor(append([]grpc.UnaryClientInterceptor{deadlineInterceptor(o.timeout)}, o.interceptors...)...),
+		// the idle connection is pinged so that a broken one is detected before the next call
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                o.keepaliveTime,
+			Timeout:             o.keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: o.timeout,
+		}),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}
+	dialOptions = append(dialOptions, o.dialOptions...)
+
+	conn, err := grpc.NewClient(target, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", target, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		GreeterClient: protos.NewGreeterClient(conn),
+		conn:          conn,
+		target:        target,
+		stopWatching:  cancel,
+		watcherDone:   make(chan struct{}),
+		state:         conn.GetState(),
+	}
+
+	if err := c.registerMetrics(); err != nil {
+		cancel()
+		_ = conn.Close()
+		return nil, err
+	}
+
+	go c.watchState(ctx)
+
+	return c, nil
+}
+
+// Conn returns the channel, i.e. to create clients of other services on the same server
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// State returns the connectivity state of the channel
+func (c *Client) State() connectivity.State {
+	return c.conn.GetState()
+}
+
+// Close stops the metrics and closes the channel, the pending calls are cancelled
+func (c *Client) Close() error {
+	c.stopWatching()
+	<-c.watcherDone
+
+	if c.registration != nil {
+		_ = c.registration.Unregister()
+	}
+	return c.conn.Close()
+}
+
+// registerMetrics records the state of the channel, i.e. to alert when it is in TRANSIENT_FAILURE
+func (c *Client) registerMetrics() error {
+	meter := otel.Meter(meterName)
+
+	var err error
+	c.transitions, err = meter.Int64Counter("grpc.client.connection.transitions",
+		metric.WithDescription("Number of connectivity state changes of the channel"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC client metrics: %w", err)
+	}
+
+	stateGauge, err := meter.Int64ObservableGauge("grpc.client.connection.state",
+		metric.WithDescription("1 for the current connectivity state of the channel, 0 for the others"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create gRPC client metrics: %w", err)
+	}
+
+	c.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		c.mu.Lock()
+		current := c.state
+		c.mu.Unlock()
+
+		for _, state := range []connectivity.State{
+			connectivity.Idle, connectivity.Connecting, connectivity.Ready, connectivity.TransientFailure, connectivity.Shutdown,
+		} {
+			value := int64(0)
+			if state == current {
+				value = 1
+			}
+			o.ObserveInt64(stateGauge, value, metric.WithAttributes(
+				attribute.String("rpc.grpc.target", c.target),
+				attribute.String("rpc.grpc.connection.state", state.String()),
+			))
+		}
+		return nil
+	}, stateGauge)
+	if err != nil {
+		return fmt.Errorf("failed to register gRPC client metrics: %w", err)
+	}
+
+	return nil
+}
+
+// watchState counts and logs the state changes, without triggering the connection
+func (c *Client) watchState(ctx context.Context) {
+	defer close(c.watcherDone)
+
+	state := c.conn.GetState()
+	for c.conn.WaitForStateChange(ctx, state) {
+		state = c.conn.GetState()
+
+		c.mu.Lock()
+		c.state = state
+		c.mu.Unlock()
+
+		c.transitions.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("rpc.grpc.target", c.target),
+			attribute.String("rpc.grpc.connection.state", state.String()),
+		))
+		slog.Info("gRPC connection state changed", slog.String("target", c.target), slog.String("state", state.String()))
+	}
+}
+
+// deadlineInterceptor sets the default deadline on the calls without one, the deadline of
+// the context is then sent to the server with the grpc-timeout header
+func deadlineInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
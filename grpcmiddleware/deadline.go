@@ -0,0 +1,49 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// Attribute of the span of the RPCs with the deadline capped
+const deadlineCappedKey = attribute.Key("rpc.grpc.deadline_capped")
+
+// unaryDeadline cancels the RPCs running longer than maxDeadline, whatever deadline the client set
+func unaryDeadline(maxDeadline time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if maxDeadline <= 0 || isHealthCheck(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := capDeadline(ctx, maxDeadline)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func streamDeadline(maxDeadline time.Duration) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		// the Watch streams of the health service last as long as the connection
+		if maxDeadline <= 0 || isHealthCheck(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		ctx, cancel := capDeadline(ss.Context(), maxDeadline)
+		defer cancel()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// capDeadline sets the deadline to maxDeadline from now if the context has none or a later one
+func capDeadline(ctx context.Context, maxDeadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= maxDeadline {
+		return ctx, func() {}
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(deadlineCappedKey.Bool(true))
+	return context.WithTimeout(ctx, maxDeadline)
+}
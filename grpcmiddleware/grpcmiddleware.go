@@ -0,0 +1,95 @@
+// Package grpcmiddleware has the interceptors of the gRPC server, chained in this order:
+// logging, panic recovery, max deadline and validation of the requests
+package grpcmiddleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Longest deadline when not set with WithMaxDeadline
+const defaultMaxDeadline = 30 * time.Second
+
+// Option changes a setting of the interceptors
+type Option func(*options)
+
+type options struct {
+	maxDeadline time.Duration
+	validation  bool
+}
+
+// WithMaxDeadline caps the deadline of the RPCs, the ones without a deadline get this one,
+// 0 leaves the deadlines set by the clients
+func WithMaxDeadline(d time.Duration) Option {
+	return func(o *options) {
+		o.maxDeadline = d
+	}
+}
+
+// WithoutValidation disables the validation of the requests with the rules of validate.proto
+func WithoutValidation() Option {
+	return func(o *options) {
+		o.validation = false
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		maxDeadline: defaultMaxDeadline,
+		validation:  true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// UnaryServerInterceptors returns the interceptors of the unary RPCs, to be passed to
+// grpc.ChainUnaryInterceptor with the otelgrpc stats handler so that the span of the RPC is in the context
+func UnaryServerInterceptors(opts ...Option) []grpc.UnaryServerInterceptor {
+	o := newOptions(opts...)
+
+	interceptors := []grpc.UnaryServerInterceptor{
+		unaryLogging,
+		unaryRecovery,
+		unaryDeadline(o.maxDeadline),
+	}
+	if o.validation {
+		interceptors = append(interceptors, unaryValidation)
+	}
+	return interceptors
+}
+
+// StreamServerInterceptors returns the interceptors of the streaming RPCs, to be passed to
+// grpc.ChainStreamInterceptor, each message received is validated
+func StreamServerInterceptors(opts ...Option) []grpc.StreamServerInterceptor {
+	o := newOptions(opts...)
+
+	interceptors := []grpc.StreamServerInterceptor{
+		streamLogging,
+		streamRecovery,
+		streamDeadline(o.maxDeadline),
+	}
+	if o.validation {
+		interceptors = append(interceptors, streamValidation)
+	}
+	return interceptors
+}
+
+// isHealthCheck matches the RPCs of the grpc.health.v1 service, frequent and, for Watch, long-lived
+func isHealthCheck(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.v1.Health/")
+}
+
+// serverStream replaces the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
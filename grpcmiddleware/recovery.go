@@ -0,0 +1,53 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unaryRecovery returns codes.Internal instead of crashing the process when the handler panics
+func unaryRecovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = recovered(ctx, info.FullMethod, p)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecovery(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = recovered(ss.Context(), info.FullMethod, p)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recovered records the panic, with the stack trace, on the span of the RPC. The details
+// are not sent to the client.
+func recovered(ctx context.Context, method string, p any) error {
+	stack := string(debug.Stack())
+	err := fmt.Errorf("panic: %v", p)
+
+	span := trace.SpanFromContext(ctx)
+	// called while panicking, the stack trace still has the frames of the handler
+	span.RecordError(err, trace.WithStackTrace(true))
+	span.SetStatus(otelcodes.Error, err.Error())
+
+	slog.ErrorContext(ctx, "Recovered from panic",
+		slog.String("method", method),
+		slog.Any("panic", p),
+		slog.String("stack", stack),
+	)
+
+	return status.Error(codes.Internal, "internal error")
+}
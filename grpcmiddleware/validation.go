@@ -0,0 +1,149 @@
+package grpcmiddleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	protos "github.com/emanuelef/go-fiber-honeycomb/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// unaryValidation rejects with codes.InvalidArgument the requests not matching the rules
+// set on their fields with the (protos.rules) option, see proto/validate.proto
+func unaryValidation(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if m, ok := req.(proto.Message); ok {
+		if err := Validate(m); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func streamValidation(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &validatingStream{ServerStream: ss})
+}
+
+// validatingStream validates each message received
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if msg, ok := m.(proto.Message); ok {
+		return Validate(msg)
+	}
+	return nil
+}
+
+// Validate checks the message, and the messages in its fields, against the rules of the fields.
+// The error is a codes.InvalidArgument status with the violations in a BadRequest detail.
+func Validate(m proto.Message) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	validateMessage(m.ProtoReflect(), "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.GetField() + " " + v.GetDescription()
+	}
+
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid %s: %s",
+		m.ProtoReflect().Descriptor().Name(), strings.Join(descriptions, ", ")))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func validateMessage(m protoreflect.Message, prefix string, violations *[]*errdetails.BadRequest_FieldViolation) {
+	fields := m.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		rules, _ := proto.GetExtension(fd.Options(), protos.E_Rules).(*protos.FieldRules)
+		if rules != nil {
+			for _, description := range checkRules(m, fd, rules) {
+				*violations = append(*violations, &errdetails.BadRequest_FieldViolation{
+					Field:       path,
+					Description: description,
+				})
+			}
+		}
+
+		if fd.Kind() != protoreflect.MessageKind || fd.IsMap() || !m.Has(fd) {
+			continue
+		}
+		if fd.IsList() {
+			list := m.Get(fd).List()
+			for j := range list.Len() {
+				validateMessage(list.Get(j).Message(), fmt.Sprintf("%s[%d].", path, j), violations)
+			}
+			continue
+		}
+		validateMessage(m.Get(fd).Message(), path+".", violations)
+	}
+}
+
+// checkRules returns the descriptions of the rules the field doesn't match
+func checkRules(m protoreflect.Message, fd protoreflect.FieldDescriptor, rules *protos.FieldRules) []string {
+	if !m.Has(fd) {
+		if rules.GetRequired() {
+			return []string{"is required"}
+		}
+		// the length and range rules apply only to the fields set
+		return nil
+	}
+	if fd.IsList() || fd.IsMap() {
+		return nil
+	}
+
+	var failed []string
+	value := m.Get(fd)
+
+	if fd.Kind() == protoreflect.StringKind {
+		length := uint32(utf8.RuneCountInString(value.String()))
+		if rules.MinLen != nil && length < rules.GetMinLen() {
+			failed = append(failed, fmt.Sprintf("must be at least %d characters", rules.GetMinLen()))
+		}
+		if rules.MaxLen != nil && length > rules.GetMaxLen() {
+			failed = append(failed, fmt.Sprintf("must be at most %d characters", rules.GetMaxLen()))
+		}
+	}
+
+	if n, ok := integer(fd, value); ok {
+		if rules.Min != nil && n < rules.GetMin() {
+			failed = append(failed, fmt.Sprintf("must be at least %d", rules.GetMin()))
+		}
+		if rules.Max != nil && n > rules.GetMax() {
+			failed = append(failed, fmt.Sprintf("must be at most %d", rules.GetMax()))
+		}
+	}
+
+	return failed
+}
+
+// integer returns the value of the integer fields
+func integer(fd protoreflect.FieldDescriptor, value protoreflect.Value) (int64, bool) {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return value.Int(), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return int64(value.Uint()), true
+	default:
+		return 0, false
+	}
+}
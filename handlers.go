@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/grpcclient"
+	"github.com/emanuelef/go-fiber-honeycomb/healthcheck"
+	protos "github.com/emanuelef/go-fiber-honeycomb/proto"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/go-resty/resty/v2"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/status"
+)
+
+// Messages sent by the streaming endpoints, set with the count query parameter
+const (
+	defaultStreamCount = 3
+	maxStreamCount     = 100
+)
+
+// handlers serves the routes of the main app calling the upstreams with the shared clients
+type handlers struct {
+	cfg               *config.Config
+	externalURL       string
+	secondaryHelloURL string
+	httpClient        *http.Client
+	restyClient       *resty.Client
+	greeter           *grpcclient.Client
+	readiness         *healthcheck.Checker
+}
+
+// registerRoutes adds the routes of the main app
+func registerRoutes(app *server.FiberApp, h *handlers) {
+	app.Get("/health", h.health)
+	app.Get("/debug/config", h.debugConfig)
+	app.Get("/hello", hello)
+	app.Get("/hello-child", helloChild)
+	app.Get("/hello-otelhttp", h.helloOtelhttp)
+	app.Get("/hello-http-client", h.helloHTTPClient)
+	app.Get("/hello-resty", h.helloResty)
+	app.Get("/hello-grpc", h.helloGRPC)
+	app.Get("/hello-grpc-server-stream", h.helloGRPCServerStream)
+	app.Get("/hello-grpc-client-stream", h.helloGRPCClientStream)
+	app.Get("/hello-grpc-bidi-stream", h.helloGRPCBidiStream)
+}
+
+// The context will carry the traceid and span id
+// so once is passed it can be used access to the current span
+// or create a child one, the function below will work if placed anywhere
+// even in other packages
+func exampleChildSpan(ctx context.Context) {
+	// Get the current span from context
+	// this can be needed to add an attribute or an event
+	// but not necessary if the intention is then just to create a child span
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("stringAttr", "Ciao"))
+
+	// Create a child span
+	_, anotherSpan := tracer.Start(ctx, "child-operation")
+	anotherSpan.AddEvent("ciao")
+	time.Sleep(10 * time.Millisecond)
+	anotherSpan.End()
+}
+
+// streamCount reads the number of messages of the streaming endpoints from the count query parameter
+func streamCount(c *fiber.Ctx) (int, error) {
+	count := c.QueryInt("count", defaultStreamCount)
+	if count < 1 || count > maxStreamCount {
+		return 0, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxStreamCount))
+	}
+	return count, nil
+}
+
+// get requests the URL with the shared client and returns the response status, the body
+// is read and closed so that the span ends and the connection is reused
+func (h *handlers) get(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return resp.Status, nil
+}
+
+// health is just to check health and an example of a very frequent request
+// that we might not want to generate traces.
+// With ?deep=true it returns the same report of /readyz.
+func (h *handlers) health(c *fiber.Ctx) error {
+	if !c.QueryBool("deep") {
+		return c.Send(nil)
+	}
+
+	report := h.readiness.Run(c.UserContext())
+	if !report.Healthy() {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(report)
+}
+
+// debugConfig returns the effective config, with the secrets redacted
+func (h *handlers) debugConfig(c *fiber.Ctx) error {
+	return c.JSON(h.cfg.Redacted())
+}
+
+// hello is a basic GET API to show the OtelFiber middleware is taking
+// care of creating the span when called
+func hello(c *fiber.Ctx) error {
+	return c.Send(nil)
+}
+
+// helloChild creates a child span
+func helloChild(c *fiber.Ctx) error {
+	_, childSpan := tracer.Start(c.UserContext(), "custom-child-span")
+	time.Sleep(10 * time.Millisecond) // simulate some work
+	childSpan.End()
+	return c.Send(nil)
+}
+
+// helloOtelhttp runs HTTP requests to a public URL and to the secondary app with the shared client
+func (h *handlers) helloOtelhttp(c *fiber.Ctx) error {
+	if _, err := h.get(c.UserContext(), h.externalURL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	// make sure secondary app is running
+	if _, err := h.get(c.UserContext(), h.secondaryHelloURL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	// Get current span and add new attributes
+	span := trace.SpanFromContext(c.UserContext())
+	span.SetAttributes(attribute.Bool("isTrue", true), attribute.String("stringAttr", "Ciao"))
+
+	// Create a child span
+	ctx, childSpan := tracer.Start(c.UserContext(), "custom-span")
+	time.Sleep(10 * time.Millisecond)
+	defer childSpan.End()
+	status, err := h.get(ctx, h.externalURL)
+	if err != nil {
+		childSpan.RecordError(err)
+		childSpan.SetStatus(codes.Error, err.Error())
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Add an event to the current span
+	span.AddEvent("Done Activity")
+	exampleChildSpan(ctx)
+	return c.SendString(status)
+}
+
+// helloHTTPClient is like helloOtelhttp, building each request and reading the reply of the secondary app
+func (h *handlers) helloHTTPClient(c *fiber.Ctx) error {
+	req, err := http.NewRequestWithContext(c.UserContext(), "GET", h.externalURL, nil)
+	if err != nil {
+		return err
+	}
+
+	// Needed to propagate the traceparent remotely if not setting the otelhttp.NewTransport
+	// otel.GetTextMapPropagator().Inject(c.UserContext(), propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	req, err = http.NewRequestWithContext(c.UserContext(), "GET", h.secondaryHelloURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err = h.httpClient.Do(req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	result := []map[string]any{}
+	_ = json.Unmarshal(body, &result)
+
+	return c.SendString(resp.Status)
+}
+
+// helloResty is like helloOtelhttp with the Resty client
+func (h *handlers) helloResty(c *fiber.Ctx) error {
+	// get current span
+	span := trace.SpanFromContext(c.UserContext())
+
+	// add events to span
+	time.Sleep(70 * time.Millisecond)
+	span.AddEvent("Done first fake long running task")
+	time.Sleep(90 * time.Millisecond)
+	span.AddEvent("Done second fake long running task")
+
+	// The log record is correlated to the trace through the context
+	slog.WarnContext(c.UserContext(), "Example log")
+
+	restyReq := h.restyClient.R()
+	restyReq.SetContext(c.UserContext()) // makes it possible to use the HTTP request trace_id
+
+	// Needed to propagate the traceparent remotely if not setting the otelhttp.NewTransport
+	// otel.GetTextMapPropagator().Inject(c.UserContext(), propagation.HeaderCarrier(restyReq.Header))
+
+	// run HTTP request first time
+	resp, err := restyReq.Get(h.externalURL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	// run second time and notice http.getconn time compared to first one
+	if _, err := restyReq.Get(h.externalURL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if _, err := restyReq.Get(h.secondaryHelloURL); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	// simulate some post processing
+	span.AddEvent("Start post processing")
+	time.Sleep(50 * time.Millisecond)
+
+	return c.SendString(resp.Status())
+}
+
+// helloGRPC calls SayHello of the gRPC server
+func (h *handlers) helloGRPC(c *fiber.Ctx) error {
+	// the deadline of the context, or GRPC_TIMEOUT, is propagated to the server
+	r, err := h.greeter.SayHello(c.UserContext(), &protos.HelloRequest{Greeting: "ciao"})
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "SayHello failed", slog.Any("error", err))
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	slog.InfoContext(c.UserContext(), "Greeting received", slog.String("reply", r.GetReply()))
+
+	return c.Send(nil)
+}
+
+// helloGRPCServerStream relays the replies of the server stream as Server-Sent Events, i.e.
+// curl -N "localhost:8080/hello-grpc-server-stream?count=5&interval_ms=500"
+func (h *handlers) helloGRPCServerStream(c *fiber.Ctx) error {
+	count, err := streamCount(c)
+	if err != nil {
+		return err
+	}
+
+	// the stream outlives the handler, it is cancelled when the relay ends
+	ctx, cancel := context.WithCancel(c.UserContext())
+	stream, err := h.greeter.SayHelloServerStream(ctx, &protos.HelloStreamRequest{
+		Greeting:   "ciao",
+		Count:      int32(count),
+		IntervalMs: int32(c.QueryInt("interval_ms")),
+	})
+	if err != nil {
+		cancel()
+		slog.ErrorContext(c.UserContext(), "SayHelloServerStream failed", slog.Any("error", err))
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		for {
+			reply, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				fmt.Fprint(w, "event: end\ndata:\n\n")
+				_ = w.Flush()
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", status.Convert(err).Message())
+				_ = w.Flush()
+				return
+			}
+
+			fmt.Fprintf(w, "data: %s\n\n", reply.GetReply())
+			// fails when the client disconnected, the stream is then cancelled
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// helloGRPCClientStream sends count greetings and returns the single reply of the server
+func (h *handlers) helloGRPCClientStream(c *fiber.Ctx) error {
+	count, err := streamCount(c)
+	if err != nil {
+		return err
+	}
+
+	stream, err := h.greeter.SayHelloClientStream(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	for i := range count {
+		// io.EOF means that the server ended the stream, the error is returned by CloseAndRecv
+		if err := stream.Send(&protos.HelloRequest{Greeting: fmt.Sprintf("ciao #%d", i+1)}); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+	}
+
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "SayHelloClientStream failed", slog.Any("error", err))
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.SendString(summary.GetReply())
+}
+
+// helloGRPCBidiStream sends count greetings while receiving the replies, returned as a JSON array
+func (h *handlers) helloGRPCBidiStream(c *fiber.Ctx) error {
+	count, err := streamCount(c)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(c.UserContext())
+	defer cancel()
+
+	stream, err := h.greeter.SayHelloBidiStream(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		for i := range count {
+			if err := stream.Send(&protos.HelloRequest{Greeting: fmt.Sprintf("ciao #%d", i+1)}); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	replies := []string{}
+	for {
+		reply, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "SayHelloBidiStream failed", slog.Any("error", err))
+			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		}
+		replies = append(replies, reply.GetReply())
+	}
+
+	if err := <-sendErr; err != nil && !errors.Is(err, io.EOF) {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return c.JSON(replies)
+}
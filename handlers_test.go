@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emanuelef/go-fiber-honeycomb/telemetrytest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestHelloChild(t *testing.T) {
+	rec := telemetrytest.NewRecorder(t)
+	app := telemetrytest.NewFiberApp(t)
+	app.Get("/hello-child", helloChild)
+
+	resp := telemetrytest.Do(t, app, httptest.NewRequest(http.MethodGet, "/hello-child", nil))
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+
+	server := rec.RequireServerSpan(t, "/hello-child")
+	rec.RequireChild(t, server, "custom-child-span")
+	telemetrytest.RequireAttribute(t, server, attribute.Int("http.status_code", http.StatusOK))
+}
@@ -0,0 +1,175 @@
+// Package healthcheck runs the checks of the dependencies of an app, i.e. for a readiness probe,
+// and reports the outcome of each one
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Time limit of each check when not set with WithTimeout
+const defaultTimeout = 2 * time.Second
+
+// Values of the status of the report and of the checks
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Check returns an error when the dependency is not healthy
+type Check func(ctx context.Context) error
+
+// Report is the outcome of the checks, Status is StatusOK only if all the checks,
+// except the non critical ones, passed
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Healthy reports if all the critical checks passed
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Option adds a check or changes a setting of the Checker
+type Option func(*Checker)
+
+// WithCheck adds a named check
+func WithCheck(name string, check Check) Option {
+	return func(c *Checker) {
+		c.checks[name] = check
+	}
+}
+
+// WithNonCriticalCheck adds a named check that is reported without making the report fail,
+// i.e. for a dependency the app can run without
+func WithNonCriticalCheck(name string, check Check) Option {
+	return func(c *Checker) {
+		c.checks[name] = check
+		c.nonCritical[name] = true
+	}
+}
+
+// WithTimeout sets the time limit of each check
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// Checker runs the checks concurrently, each one with its own timeout
+type Checker struct {
+	checks      map[string]Check
+	nonCritical map[string]bool
+	timeout     time.Duration
+}
+
+// New creates a Checker, without checks it always reports StatusOK
+func New(opts ...Option) *Checker {
+	c := &Checker{
+		checks:      map[string]Check{},
+		nonCritical: map[string]bool{},
+		timeout:     defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run runs all the checks and waits for them to complete or time out
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			result := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Status = StatusFailed
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil && !c.nonCritical[name] {
+				report.Status = StatusFailed
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// HTTP expects a 2xx from the URL, the client should not be instrumented
+// so that the checks are not traced
+func HTTP(client *http.Client, url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// GRPC asks the grpc.health.v1 service of the server if the service is SERVING
+func GRPC(conn *grpc.ClientConn, service string) Check {
+	return func(ctx context.Context) error {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%s is %s", service, resp.GetStatus())
+		}
+		return nil
+	}
+}
+
+// OTLPCollector checks that the OTLP endpoint accepts connections,
+// it always passes when the traces are not exported with OTLP
+func OTLPCollector() Check {
+	return func(ctx context.Context) error {
+		if !otel_instrumentation.UsesOTLPExporter() {
+			return nil
+		}
+		return otel_instrumentation.CheckCollector(ctx)
+	}
+}
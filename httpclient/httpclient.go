@@ -0,0 +1,178 @@
+// Package httpclient creates the instrumented HTTP clients shared by the handlers,
+// reusing the connections across the requests instead of creating a client per request
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Defaults of the transport, the idle connections per host are raised from the 2
+// of http.DefaultTransport as the apps call the same few hosts many times
+const (
+	defaultTimeout             = 10 * time.Second
+	defaultDialTimeout         = 5 * time.Second
+	defaultTLSHandshakeTimeout = 5 * time.Second
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 20
+)
+
+// Option changes a setting of the clients created by New
+type Option func(*options)
+
+type options struct {
+	timeout             time.Duration
+	hostTimeouts        map[string]time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	clientTrace         bool
+}
+
+// WithTimeout sets the time limit of the requests to the hosts without a specific timeout,
+// including reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHostTimeouts sets the time limit of the requests by host name, i.e. "pokeapi.co"
+func WithHostTimeouts(timeouts map[string]time.Duration) Option {
+	return func(o *options) {
+		for host, timeout := range timeouts {
+			o.hostTimeouts[host] = timeout
+		}
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the connections kept open for reuse with each host
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.maxIdleConnsPerHost = n
+	}
+}
+
+// WithMaxConnsPerHost limits the connections with each host, 0 means no limit
+func WithMaxConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.maxConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection is kept open
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleConnTimeout = timeout
+	}
+}
+
+// WithoutClientTrace disables the child spans of the connection phases (dns, connect, tls, getconn...)
+func WithoutClientTrace() Option {
+	return func(o *options) {
+		o.clientTrace = false
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		timeout:             defaultTimeout,
+		hostTimeouts:        map[string]time.Duration{},
+		maxIdleConns:        defaultMaxIdleConns,
+		maxIdleConnsPerHost: defaultMaxIdleConnsPerHost,
+		idleConnTimeout:     defaultIdleConnTimeout,
+		clientTrace:         true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// New creates a client to be shared by the handlers, its requests create a span with the
+// trace context propagated to the server and, by default, child spans of the connection phases.
+// The second request to the same host shows the connection reused in http.getconn.
+func New(opts ...Option) *http.Client {
+	return &http.Client{Transport: NewTransport(opts...)}
+}
+
+// NewTransport creates the RoundTripper used by New, i.e. to be wrapped by other RoundTrippers
+func NewTransport(opts ...Option) http.RoundTripper {
+	o := newOptions(opts...)
+
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   defaultDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          o.maxIdleConns,
+		MaxIdleConnsPerHost:   o.maxIdleConnsPerHost,
+		MaxConnsPerHost:       o.maxConnsPerHost,
+		IdleConnTimeout:       o.idleConnTimeout,
+		TLSHandshakeTimeout:   defaultTLSHandshakeTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	otelOpts := []otelhttp.Option{}
+	if o.clientTrace {
+		otelOpts = append(otelOpts, otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
+			return otelhttptrace.NewClientTrace(ctx)
+		}))
+	}
+
+	return &timeoutTransport{
+		next:         otelhttp.NewTransport(base, otelOpts...),
+		timeout:      o.timeout,
+		hostTimeouts: o.hostTimeouts,
+	}
+}
+
+// timeoutTransport limits the duration of the requests by host, a single http.Client.Timeout
+// can't be used as it applies to all the hosts
+type timeoutTransport struct {
+	next         http.RoundTripper
+	timeout      time.Duration
+	hostTimeouts map[string]time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout, ok := t.hostTimeouts[req.URL.Hostname()]
+	if !ok {
+		timeout = t.timeout
+	}
+	if timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the timeout covers reading the body, the context is released when it is closed
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
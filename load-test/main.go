@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/loadgen"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+)
+
+func main() {
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
+
+	cfg, err := config.Load(config.WithSections(config.LoadTestSection))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	thresholds := make([]loadgen.Threshold, 0, len(cfg.LoadTest.Thresholds))
+	for _, s := range cfg.LoadTest.Thresholds {
+		t, err := loadgen.ParseThreshold(s)
+		if err != nil {
+			log.Fatalf("invalid LOAD_TEST_THRESHOLDS: %v", err)
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	stages := make([]loadgen.Stage, len(cfg.LoadTest.Stages))
+	for i, s := range cfg.LoadTest.Stages {
+		stages[i] = loadgen.Stage{Duration: s.Duration, Target: s.Target}
+	}
+
+	generator := loadgen.New(cfg.LoadTest.BaseURL, cfg.LoadTest.Endpoints,
+		loadgen.WithHTTPClient(loadgen.NewHTTPClient(cfg.LoadTest.RequestTimeout)),
+		loadgen.WithStages(stages...),
+		loadgen.WithThresholds(thresholds...),
+		loadgen.WithSlowest(cfg.LoadTest.Slowest),
+	)
+	report, err := generator.Run(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var traceURL func(string) string
+	if cfg.LoadTest.TraceURL != "" {
+		traceURL = func(traceID string) string {
+			return strings.ReplaceAll(cfg.LoadTest.TraceURL, "{trace_id}", traceID)
+		}
+	}
+
+	fmt.Println()
+	report.Print(os.Stdout, traceURL)
+
+	if !report.Passed() {
+		fmt.Println("\nthresholds failed")
+		os.Exit(1)
+	}
+}
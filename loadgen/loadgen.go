@@ -0,0 +1,261 @@
+// Package loadgen generates load on the endpoints of an HTTP app, ramping the number of virtual users
+// through stages like k6. Each request starts a new trace with the traceparent it sends, so that the
+// slowest requests of the report can be opened in the tracing backend by their trace ID.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/traceparent"
+)
+
+const (
+	// Time between the updates of the number of virtual users during a stage
+	rampInterval = 100 * time.Millisecond
+	// Time between the progress logs
+	progressInterval = 5 * time.Second
+	// DefaultSlowest is the number of slowest requests reported
+	DefaultSlowest = 10
+)
+
+// Stage changes linearly the number of virtual users, from the target of the previous stage, or 0,
+// to Target over Duration
+type Stage struct {
+	Duration time.Duration
+	Target   int
+}
+
+// Request is a request made by a virtual user
+type Request struct {
+	// Endpoint is the path called, with the query
+	Endpoint string
+	// TraceID of the trace started by the request
+	TraceID    string
+	Start      time.Time
+	Duration   time.Duration
+	StatusCode int
+	Err        error
+}
+
+// Failed is true for a transport error or a status code >= 400
+func (r Request) Failed() bool {
+	return r.Err != nil || r.StatusCode >= http.StatusBadRequest
+}
+
+// NewHTTPClient returns a client keeping a connection open per virtual user, instead of the 2 per host
+// of http.DefaultTransport
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 0
+	transport.MaxIdleConnsPerHost = 1000
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Option configures the Generator
+type Option func(*Generator)
+
+// WithHTTPClient sets the client of the requests, its Timeout bounding the requests
+func WithHTTPClient(c *http.Client) Option {
+	return func(g *Generator) {
+		g.httpClient = c
+	}
+}
+
+// WithStages sets the stages of the run
+func WithStages(stages ...Stage) Option {
+	return func(g *Generator) {
+		g.stages = stages
+	}
+}
+
+// WithThresholds sets the thresholds evaluated at the end of the run
+func WithThresholds(thresholds ...Threshold) Option {
+	return func(g *Generator) {
+		g.thresholds = thresholds
+	}
+}
+
+// WithSlowest sets the number of slowest requests reported
+func WithSlowest(n int) Option {
+	return func(g *Generator) {
+		g.slowest = n
+	}
+}
+
+// Generator calls the endpoints of an app in turn with the virtual users of the stages
+type Generator struct {
+	baseURL    string
+	endpoints  []string
+	httpClient *http.Client
+	stages     []Stage
+	thresholds []Threshold
+	slowest    int
+
+	// next is the index of the next endpoint called, shared by the virtual users
+	next     atomic.Uint64
+	mu       sync.Mutex
+	requests []Request
+}
+
+// New returns a Generator calling the endpoints, i.e. /hello, of the app at baseURL, by default with
+// the stages of the k6 script: 20 users in 10s, 100 in the next 20s and 0 in the last 10s
+func New(baseURL string, endpoints []string, opts ...Option) *Generator {
+	g := &Generator{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		endpoints:  endpoints,
+		httpClient: NewHTTPClient(10 * time.Second),
+		stages: []Stage{
+			{Duration: 10 * time.Second, Target: 20},
+			{Duration: 20 * time.Second, Target: 100},
+			{Duration: 10 * time.Second, Target: 0},
+		},
+		slowest: DefaultSlowest,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Run goes through the stages and returns the report of the requests made. When the context is
+// done the run stops early, the requests interrupted are not reported.
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	if len(g.endpoints) == 0 {
+		return nil, errors.New("no endpoint to call")
+	}
+	g.requests = nil
+
+	// the channels closed to stop the running virtual users
+	var vus []chan struct{}
+	var wg sync.WaitGroup
+	// scale starts or stops virtual users, the last started are stopped first and finish their request
+	scale := func(target int) {
+		for len(vus) < target {
+			stop := make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.user(ctx, stop)
+			}()
+			vus = append(vus, stop)
+		}
+		for len(vus) > target {
+			close(vus[len(vus)-1])
+			vus = vus[:len(vus)-1]
+		}
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(rampInterval)
+	defer ticker.Stop()
+	lastProgress := start
+
+	from := 0
+stages:
+	for _, stage := range g.stages {
+		stageStart := time.Now()
+		for {
+			elapsed := time.Since(stageStart)
+			if elapsed >= stage.Duration {
+				scale(stage.Target)
+				break
+			}
+			scale(from + int(float64(stage.Target-from)*float64(elapsed)/float64(stage.Duration)))
+
+			if time.Since(lastProgress) >= progressInterval {
+				lastProgress = time.Now()
+				g.logProgress(len(vus), time.Since(start))
+			}
+
+			select {
+			case <-ctx.Done():
+				break stages
+			case <-ticker.C:
+			}
+		}
+		from = stage.Target
+	}
+	scale(0)
+	wg.Wait()
+
+	return g.report(time.Since(start)), nil
+}
+
+// user calls the endpoints until it's stopped
+func (g *Generator) user(ctx context.Context, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		endpoint := g.endpoints[(g.next.Add(1)-1)%uint64(len(g.endpoints))]
+		r := g.call(ctx, endpoint)
+		if ctx.Err() != nil {
+			return
+		}
+
+		g.mu.Lock()
+		g.requests = append(g.requests, r)
+		g.mu.Unlock()
+	}
+}
+
+// call makes a request to the endpoint in a new trace
+func (g *Generator) call(ctx context.Context, endpoint string) Request {
+	sc := traceparent.NewSpanContext()
+	r := Request{Endpoint: endpoint, TraceID: sc.TraceID().String(), Start: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+endpoint, nil)
+	if err != nil {
+		r.Err = fmt.Errorf("failed to create request: %w", err)
+		return r
+	}
+	traceparent.Inject(req, sc)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		r.Duration = time.Since(r.Start)
+		r.Err = fmt.Errorf("failed to call %s: %w", endpoint, err)
+		return r
+	}
+	// the streams end with the body
+	_, err = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	r.Duration = time.Since(r.Start)
+	r.StatusCode = resp.StatusCode
+	if err != nil {
+		r.Err = fmt.Errorf("failed to read response of %s: %w", endpoint, err)
+	}
+	return r
+}
+
+func (g *Generator) logProgress(vus int, elapsed time.Duration) {
+	g.mu.Lock()
+	requests, failed := len(g.requests), 0
+	for _, r := range g.requests {
+		if r.Failed() {
+			failed++
+		}
+	}
+	g.mu.Unlock()
+
+	slog.Info("Running",
+		slog.Duration("elapsed", elapsed.Round(time.Second)),
+		slog.Int("vus", vus),
+		slog.Int("requests", requests),
+		slog.Int("failed", failed),
+	)
+}
@@ -0,0 +1,216 @@
+package loadgen
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// stats of the requests of an endpoint, or of all of them
+type stats struct {
+	// durations sorted
+	durations []time.Duration
+	failed    int
+}
+
+func (s *stats) add(r Request) {
+	s.durations = append(s.durations, r.Duration)
+	if r.Failed() {
+		s.failed++
+	}
+}
+
+func (s *stats) errorRate() float64 {
+	return float64(s.failed) / float64(len(s.durations))
+}
+
+func (s *stats) avg() time.Duration {
+	var total time.Duration
+	for _, d := range s.durations {
+		total += d
+	}
+	return total / time.Duration(len(s.durations))
+}
+
+// percentile with the nearest-rank method, 0 is the min and 100 the max
+func (s *stats) percentile(p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(s.durations))))
+	return s.durations[max(rank-1, 0)]
+}
+
+// EndpointStats summarizes the requests of an endpoint
+type EndpointStats struct {
+	// Endpoint is the path, without the query, or "all" for all the requests
+	Endpoint string
+	Requests int
+	Failed   int
+	Avg      time.Duration
+	Min      time.Duration
+	Med      time.Duration
+	P90      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+func (s *stats) summary(endpoint string) EndpointStats {
+	return EndpointStats{
+		Endpoint: endpoint,
+		Requests: len(s.durations),
+		Failed:   s.failed,
+		Avg:      s.avg(),
+		Min:      s.percentile(0),
+		Med:      s.percentile(50),
+		P90:      s.percentile(90),
+		P95:      s.percentile(95),
+		P99:      s.percentile(99),
+		Max:      s.percentile(100),
+	}
+}
+
+// Report of a run
+type Report struct {
+	Duration time.Duration
+	// Endpoints sorted by path, then the stats of all the requests
+	Endpoints  []EndpointStats
+	Thresholds []ThresholdResult
+	// Slowest requests, the slowest first
+	Slowest []Request
+	// Errors are the distinct errors of the failed requests, with their count
+	Errors map[string]int
+}
+
+// report computes the report of the requests made
+func (g *Generator) report(elapsed time.Duration) *Report {
+	r := &Report{Duration: elapsed, Errors: map[string]int{}}
+
+	all := &stats{}
+	byEndpoint := map[string]*stats{}
+	for _, req := range g.requests {
+		path := endpointPath(req.Endpoint)
+		s, ok := byEndpoint[path]
+		if !ok {
+			s = &stats{}
+			byEndpoint[path] = s
+		}
+		s.add(req)
+		all.add(req)
+
+		switch {
+		case req.Err != nil:
+			r.Errors[req.Err.Error()]++
+		case req.Failed():
+			r.Errors[fmt.Sprintf("%s returned %d", path, req.StatusCode)]++
+		}
+	}
+	for _, s := range byEndpoint {
+		slices.Sort(s.durations)
+	}
+	slices.Sort(all.durations)
+
+	for _, path := range slices.Sorted(maps.Keys(byEndpoint)) {
+		r.Endpoints = append(r.Endpoints, byEndpoint[path].summary(path))
+	}
+	if len(all.durations) > 0 {
+		r.Endpoints = append(r.Endpoints, all.summary("all"))
+	}
+
+	for _, t := range g.thresholds {
+		s := all
+		if t.Endpoint != "" {
+			s = byEndpoint[t.Endpoint]
+		}
+		r.Thresholds = append(r.Thresholds, t.evaluate(s))
+	}
+
+	slowest := slices.Clone(g.requests)
+	slices.SortFunc(slowest, func(a, b Request) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	r.Slowest = slowest[:min(g.slowest, len(slowest))]
+	return r
+}
+
+// Passed is true when all the thresholds passed
+func (r *Report) Passed() bool {
+	for _, t := range r.Thresholds {
+		if !t.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes the report to w, linking the slowest requests to their trace with traceURL when not nil
+func (r *Report) Print(w io.Writer, traceURL func(traceID string) string) {
+	total := 0
+	if len(r.Endpoints) > 0 {
+		total = r.Endpoints[len(r.Endpoints)-1].Requests
+	}
+	fmt.Fprintf(w, "%d requests in %s, %.1f/s\n\n", total, r.Duration.Round(time.Second), float64(total)/r.Duration.Seconds())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "endpoint\trequests\tfailed\tavg\tmin\tmed\tp90\tp95\tp99\tmax")
+	for _, s := range r.Endpoints {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Endpoint, s.Requests, s.Failed,
+			formatDuration(s.Avg), formatDuration(s.Min), formatDuration(s.Med), formatDuration(s.P90),
+			formatDuration(s.P95), formatDuration(s.P99), formatDuration(s.Max))
+	}
+	_ = tw.Flush()
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(w, "\nerrors")
+		for _, err := range slices.Sorted(maps.Keys(r.Errors)) {
+			fmt.Fprintf(w, "  %d x %s\n", r.Errors[err], err)
+		}
+	}
+
+	if len(r.Thresholds) > 0 {
+		fmt.Fprintln(w, "\nthresholds")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, t := range r.Thresholds {
+			outcome := "PASS"
+			if !t.OK {
+				outcome = "FAIL"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", outcome, t.Threshold, t.Value)
+		}
+		_ = tw.Flush()
+	}
+
+	if len(r.Slowest) > 0 {
+		fmt.Fprintln(w, "\nslowest requests")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, req := range r.Slowest {
+			status := fmt.Sprint(req.StatusCode)
+			if req.Err != nil {
+				status = "error"
+			}
+			link := req.TraceID
+			if traceURL != nil {
+				link = traceURL(req.TraceID)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", formatDuration(req.Duration), status, req.Endpoint, link)
+		}
+		_ = tw.Flush()
+	}
+}
+
+// endpointPath removes the query of the endpoint
+func endpointPath(endpoint string) string {
+	path, _, _ := strings.Cut(endpoint, "?")
+	return path
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Microsecond).String()
+}
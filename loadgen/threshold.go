@@ -0,0 +1,140 @@
+package loadgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metric of an error rate threshold, the others are the latencies
+const errorRateMetric = "error_rate"
+
+// thresholdPattern matches i.e. p99<1.5s, error_rate<=0.01 or /hello-resty:avg<300ms
+var thresholdPattern = regexp.MustCompile(`^(?:(/[^:]*):)?(avg|min|med|max|p\(?[0-9.]+\)?|error_rate)\s*(<=|<|>=|>)\s*(\S+)$`)
+
+// Threshold fails the run when a metric, of all the requests or of an endpoint, is out of bounds
+type Threshold struct {
+	// Endpoint is the path the metric is computed on, all the requests when empty
+	Endpoint string
+	// Metric is avg, min, med, max, a percentile like p99 or p99.9, or error_rate
+	Metric string
+	// Op is <, <=, > or >=
+	Op string
+	// Latency is the bound of the latency metrics, ErrorRate the one of error_rate
+	Latency   time.Duration
+	ErrorRate float64
+
+	percentile float64
+	// source is the threshold as parsed
+	source string
+}
+
+// ParseThreshold parses a threshold like p99<1.5s, error_rate<0.01 or, for an endpoint, /hello-resty:p95<500ms
+func ParseThreshold(s string) (Threshold, error) {
+	m := thresholdPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, i.e. p99<1.5s, error_rate<0.01 or /hello:avg<100ms", s)
+	}
+
+	t := Threshold{Endpoint: m[1], Metric: m[2], Op: m[3], source: strings.Join(strings.Fields(s), "")}
+	if t.Metric == errorRateMetric {
+		rate, err := strconv.ParseFloat(m[4], 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q, the error rate must be between 0 and 1", s)
+		}
+		t.ErrorRate = rate
+		return t, nil
+	}
+
+	latency, err := time.ParseDuration(m[4])
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, the latency must be a duration: %w", s, err)
+	}
+	t.Latency = latency
+
+	switch t.Metric {
+	case "min":
+		t.percentile = 0
+	case "med":
+		t.percentile = 50
+	case "max":
+		t.percentile = 100
+	case "avg":
+	default:
+		// p99 or p(99) like in k6
+		p, err := strconv.ParseFloat(strings.Trim(t.Metric[1:], "()"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q, the percentile must be between 0 and 100", s)
+		}
+		t.percentile = p
+	}
+	return t, nil
+}
+
+func (t Threshold) String() string {
+	if t.source != "" {
+		return t.source
+	}
+	var b strings.Builder
+	if t.Endpoint != "" {
+		b.WriteString(t.Endpoint + ":")
+	}
+	b.WriteString(t.Metric + t.Op)
+	if t.Metric == errorRateMetric {
+		b.WriteString(strconv.FormatFloat(t.ErrorRate, 'f', -1, 64))
+	} else {
+		b.WriteString(t.Latency.String())
+	}
+	return b.String()
+}
+
+// ThresholdResult is the value of the metric of a threshold at the end of the run
+type ThresholdResult struct {
+	Threshold
+	// Value of the metric, a duration or an error rate
+	Value string
+	OK    bool
+}
+
+// evaluate checks the threshold against the stats of its endpoint
+func (t Threshold) evaluate(s *stats) ThresholdResult {
+	if s == nil || len(s.durations) == 0 {
+		return ThresholdResult{Threshold: t, Value: "no requests"}
+	}
+
+	if t.Metric == errorRateMetric {
+		rate := s.errorRate()
+		return ThresholdResult{
+			Threshold: t,
+			Value:     strconv.FormatFloat(rate, 'f', 4, 64),
+			OK:        compare(rate, t.Op, t.ErrorRate),
+		}
+	}
+
+	var latency time.Duration
+	if t.Metric == "avg" {
+		latency = s.avg()
+	} else {
+		latency = s.percentile(t.percentile)
+	}
+	return ThresholdResult{
+		Threshold: t,
+		Value:     formatDuration(latency),
+		OK:        compare(float64(latency), t.Op, float64(t.Latency)),
+	}
+}
+
+func compare(value float64, op string, bound float64) bool {
+	switch op {
+	case "<":
+		return value < bound
+	case "<=":
+		return value <= bound
+	case ">":
+		return value > bound
+	default:
+		return value >= bound
+	}
+}
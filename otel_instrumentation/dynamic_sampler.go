@@ -0,0 +1,186 @@
+package otel_instrumentation
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Attribute read by Honeycomb to reweight the sampled events, a span with
+// SampleRate 10 is counted as 10 spans in the queries
+const SampleRateKey = attribute.Key("SampleRate")
+
+// Defaults used for the zero fields of DynamicSamplerConfig
+const (
+	defaultGoalThroughput = 10
+	defaultAdjustInterval = 30 * time.Second
+)
+
+// Attributes checked, in order, to find the status of the root span
+var statusAttributeKeys = []attribute.Key{"http.status_code", "http.response.status_code", "rpc.grpc.status_code"}
+
+// DynamicSamplerConfig sets the throughput targeted by the DynamicSampler
+type DynamicSamplerConfig struct {
+	// GoalThroughputPerSec is the number of traces per second to keep across all the keys
+	GoalThroughputPerSec float64
+	// AdjustInterval is how often the sample rates are recalculated from the traffic seen
+	AdjustInterval time.Duration
+	// KeyFunc groups the traces by their root span, defaults to route and status code
+	KeyFunc func(root sdktrace.ReadOnlySpan) string
+}
+
+// DynamicSampler implements Honeycomb style dynamic sampling: each key gets a sample rate so that
+// the total of the kept traces is close to the goal throughput, the rare keys (i.e. errors
+// or seldom called routes) are kept while the frequent ones are sampled more aggressively.
+// It decides on complete traces, so it is used by the TailSamplingProcessor that stamps
+// the SampleRate attribute on every span of the kept traces.
+type DynamicSampler struct {
+	cfg DynamicSamplerConfig
+
+	mu         sync.Mutex
+	counts     map[string]int
+	rates      map[string]int
+	lastAdjust time.Time
+}
+
+// NewDynamicSampler creates a sampler, the zero fields of the config use the defaults
+func NewDynamicSampler(cfg DynamicSamplerConfig) *DynamicSampler {
+	if cfg.GoalThroughputPerSec <= 0 {
+		cfg.GoalThroughputPerSec = defaultGoalThroughput
+	}
+	if cfg.AdjustInterval <= 0 {
+		cfg.AdjustInterval = defaultAdjustInterval
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = RouteStatusKey
+	}
+
+	return &DynamicSampler{
+		cfg:        cfg,
+		counts:     make(map[string]int),
+		rates:      make(map[string]int),
+		lastAdjust: time.Now(),
+	}
+}
+
+// RouteStatusKey is the default key of the DynamicSampler, i.e. "/hello-resty 200"
+func RouteStatusKey(root sdktrace.ReadOnlySpan) string {
+	route := root.Name()
+	status := ""
+	for _, attr := range root.Attributes() {
+		switch {
+		case attr.Key == "http.route" && attr.Value.AsString() != "":
+			route = attr.Value.AsString()
+		case status == "" && isStatusKey(attr.Key):
+			status = attr.Value.Emit()
+		}
+	}
+	return route + " " + status
+}
+
+func isStatusKey(key attribute.Key) bool {
+	for _, k := range statusAttributeKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Sample returns if the trace of the root span is kept and its sample rate
+func (s *DynamicSampler) Sample(root sdktrace.ReadOnlySpan) (bool, int) {
+	key := s.cfg.KeyFunc(root)
+
+	s.mu.Lock()
+	if now := time.Now(); now.Sub(s.lastAdjust) >= s.cfg.AdjustInterval {
+		s.adjust()
+		s.lastAdjust = now
+	}
+	s.counts[key]++
+	rate, ok := s.rates[key]
+	s.mu.Unlock()
+
+	// new keys are kept until the next adjustment
+	if !ok || rate <= 1 {
+		return true, 1
+	}
+
+	// the random part of the trace id keeps about one trace every rate
+	traceID := root.SpanContext().TraceID()
+	return binary.BigEndian.Uint64(traceID[8:])%uint64(rate) == 0, rate
+}
+
+// adjust recalculates the rates from the traces counted in the last interval,
+// starting from the rarest keys the budget they don't use is left to the others.
+// Must be called holding the lock.
+func (s *DynamicSampler) adjust() {
+	keys := make([]string, 0, len(s.counts))
+	for key := range s.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return s.counts[keys[i]] < s.counts[keys[j]] })
+
+	remaining := s.cfg.GoalThroughputPerSec * s.cfg.AdjustInterval.Seconds()
+	rates := make(map[string]int, len(keys))
+
+	for i, key := range keys {
+		count := float64(s.counts[key])
+		keyGoal := remaining / float64(len(keys)-i)
+
+		rate := 1
+		if count > keyGoal && keyGoal > 0 {
+			rate = int(math.Ceil(count / keyGoal))
+		}
+
+		rates[key] = rate
+		remaining -= count / float64(rate)
+	}
+
+	s.rates = rates
+	s.counts = make(map[string]int, len(keys))
+}
+
+// WithDynamicSampling samples with a DynamicSampler the traces not kept by the other
+// tail sampling conditions, enabling the TailSamplingProcessor if needed
+func WithDynamicSampling(cfg DynamicSamplerConfig) Option {
+	return func(c *Config) {
+		if c.TailSampling == nil {
+			c.TailSampling = &TailSamplingConfig{}
+		}
+		c.TailSampling.DynamicSampler = NewDynamicSampler(cfg)
+	}
+}
+
+// sampleRateSpan sets the SampleRate attribute of a span that already ended,
+// replacing the one of the head sampler
+type sampleRateSpan struct {
+	sdktrace.ReadOnlySpan
+	rate int
+}
+
+func (s sampleRateSpan) Attributes() []attribute.KeyValue {
+	attrs := s.ReadOnlySpan.Attributes()
+	// copy to avoid changing the slice held by the span
+	stamped := make([]attribute.KeyValue, 0, len(attrs)+1)
+	for _, attr := range attrs {
+		if attr.Key != SampleRateKey {
+			stamped = append(stamped, attr)
+		}
+	}
+	return append(stamped, SampleRateKey.Int(s.rate))
+}
+
+// headSampleRate returns the SampleRate set by the head sampler on the span, 1 if not set
+func headSampleRate(span sdktrace.ReadOnlySpan) int {
+	for _, attr := range span.Attributes() {
+		if attr.Key == SampleRateKey && attr.Value.AsInt64() > 1 {
+			return int(attr.Value.AsInt64())
+		}
+	}
+	return 1
+}
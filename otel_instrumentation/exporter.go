@@ -0,0 +1,180 @@
+package otel_instrumentation
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Values accepted by OTEL_TRACES_EXPORTER
+const (
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+	ExporterFile    = "file"
+	ExporterNone    = "none"
+)
+
+// Values accepted by OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// File used by the file exporter when OTEL_TRACES_EXPORTER_FILE is not set
+const defaultTracesFile = "traces.json"
+
+// Time allowed to open a connection to the collector in CheckCollector
+const collectorDialTimeout = 2 * time.Second
+
+// NewSpanExporterFromEnv creates the exporter selected by OTEL_TRACES_EXPORTER
+// and OTEL_EXPORTER_OTLP_PROTOCOL (or OTEL_EXPORTER_OTLP_TRACES_PROTOCOL),
+// when they are not set the OTLP gRPC exporter is used to send data to Honeycomb
+func NewSpanExporterFromEnv(ctx context.Context) (sdktrace.SpanExporter, error) {
+	return NewSpanExporter(ctx, os.Getenv("OTEL_TRACES_EXPORTER"), signalProtocol("TRACES"))
+}
+
+// signalProtocol returns the OTLP protocol set for a signal (TRACES, METRICS or LOGS),
+// falling back to the one shared by all the signals
+func signalProtocol(signal string) string {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	return protocol
+}
+
+// UsesOTLPExporter reports if OTEL_TRACES_EXPORTER selects the OTLP exporter, the default
+func UsesOTLPExporter() bool {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER")))
+	return name == "" || name == ExporterOTLP
+}
+
+// CheckCollector verifies that a TCP connection can be opened to the OTLP endpoint
+// configured with OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT
+func CheckCollector(ctx context.Context) error {
+	address, err := collectorAddress()
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: collectorDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to collector %s: %w", address, err)
+	}
+	return conn.Close()
+}
+
+// collectorAddress returns the host:port of the OTLP endpoint, using the
+// same defaults of the exporters when no endpoint is set
+func collectorAddress() (string, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
+	if endpoint == "" {
+		if strings.EqualFold(signalProtocol("TRACES"), ProtocolHTTPProtobuf) {
+			return "localhost:4318", nil
+		}
+		return "localhost:4317", nil
+	}
+
+	// gRPC endpoints are allowed without a scheme
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+
+	if u.Scheme == "http" {
+		return net.JoinHostPort(u.Hostname(), "80"), nil
+	}
+	return net.JoinHostPort(u.Hostname(), "443"), nil
+}
+
+// NewSpanExporter creates one of the supported exporters:
+//   - otlp: sends spans to the endpoint set in OTEL_EXPORTER_OTLP_ENDPOINT using grpc or http/protobuf
+//   - console: pretty prints spans to stdout
+//   - file: writes spans as JSON to the file set in OTEL_TRACES_EXPORTER_FILE (traces.json by default)
+//   - none: discards all the spans, useful to run the apps offline
+func NewSpanExporter(ctx context.Context, name, protocol string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ExporterOTLP:
+		return newOTLPExporter(ctx, protocol)
+	case ExporterConsole:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterFile:
+		path := os.Getenv("OTEL_TRACES_EXPORTER_FILE")
+		if path == "" {
+			path = defaultTracesFile
+		}
+		return newFileExporter(path)
+	case ExporterNone:
+		return noopExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter %q", name)
+	}
+}
+
+func newOTLPExporter(ctx context.Context, protocol string) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "", ProtocolGRPC:
+		return otlptracegrpc.New(ctx)
+	case ProtocolHTTPProtobuf:
+		return otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+	}
+}
+
+// fileExporter writes one JSON document per span, the file is closed on shutdown
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+func newFileExporter(path string) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &fileExporter{Exporter: exp, file: f}, nil
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if cErr := e.file.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// noopExporter drops every span
+type noopExporter struct{}
+
+func (noopExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+func (noopExporter) Shutdown(context.Context) error                             { return nil }
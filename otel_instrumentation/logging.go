@@ -0,0 +1,170 @@
+package otel_instrumentation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumentation scope of the records sent by the default slog logger
+const loggerName = "github.com/emanuelef/go-fiber-honeycomb"
+
+// WithLogExporter replaces the exporter selected by OTEL_LOGS_EXPORTER
+func WithLogExporter(exp sdklog.Exporter) Option {
+	return func(c *Config) {
+		c.LogExporter = exp
+	}
+}
+
+// Used to initialise the global OpenTelemetry logger provider, the records
+// are sent in batches and carry the trace and span ids of the context they are logged with
+func InitializeGlobalLoggerProvider(ctx context.Context, opts ...Option) (*sdklog.LoggerProvider, error) {
+	return initializeGlobalLoggerProvider(ctx, newConfig(opts...))
+}
+
+func initializeGlobalLoggerProvider(ctx context.Context, cfg *Config) (*sdklog.LoggerProvider, error) {
+	exp := cfg.LogExporter
+	if exp == nil {
+		envExp, err := NewLogExporterFromEnv(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize log exporter: %w", err)
+		}
+		exp = envExp
+	}
+
+	resource, err := newResource(ctx, cfg)
+	if err != nil {
+		_ = exp.Shutdown(ctx)
+		return nil, err
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exp)),
+		sdklog.WithResource(resource),
+	)
+
+	// Register the global Logger provider
+	global.SetLoggerProvider(lp)
+
+	return lp, nil
+}
+
+// NewLogExporterFromEnv creates the exporter selected by OTEL_LOGS_EXPORTER (otlp, console or none),
+// when not set the OTLP exporter is used with the protocol from
+// OTEL_EXPORTER_OTLP_LOGS_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL
+func NewLogExporterFromEnv(ctx context.Context) (sdklog.Exporter, error) {
+	name := os.Getenv("OTEL_LOGS_EXPORTER")
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ExporterOTLP:
+		switch protocol := signalProtocol("LOGS"); strings.ToLower(strings.TrimSpace(protocol)) {
+		case "", ProtocolGRPC:
+			return otlploggrpc.New(ctx)
+		case ProtocolHTTPProtobuf:
+			return otlploghttp.New(ctx)
+		default:
+			return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+		}
+	case ExporterConsole:
+		return stdoutlog.New(stdoutlog.WithPrettyPrint())
+	case ExporterNone:
+		return noopLogExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported logs exporter %q", name)
+	}
+}
+
+// NewLogger returns a slog logger writing both to stderr and to the global
+// OpenTelemetry logger provider, the lines written to stderr include the
+// trace_id and span_id found in the context passed to the *Context methods
+func NewLogger(name string) *slog.Logger {
+	return slog.New(&fanoutHandler{handlers: []slog.Handler{
+		newConsoleHandler(),
+		otelslog.NewHandler(name),
+	}})
+}
+
+func newConsoleHandler() slog.Handler {
+	return &traceContextHandler{Handler: slog.NewTextHandler(os.Stderr, nil)}
+}
+
+// traceContextHandler adds the ids of the span in the context to every record
+type traceContextHandler struct {
+	slog.Handler
+}
+
+func (h *traceContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *traceContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &traceContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *traceContextHandler) WithGroup(name string) slog.Handler {
+	return &traceContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// fanoutHandler sends every record to all of its handlers
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// noopLogExporter drops every record
+type noopLogExporter struct{}
+
+func (noopLogExporter) Export(context.Context, []sdklog.Record) error { return nil }
+func (noopLogExporter) Shutdown(context.Context) error                { return nil }
+func (noopLogExporter) ForceFlush(context.Context) error              { return nil }
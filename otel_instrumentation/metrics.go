@@ -0,0 +1,186 @@
+package otel_instrumentation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// Value accepted by OTEL_METRICS_EXPORTER to expose the metrics for a Prometheus scrape
+const ExporterPrometheus = "prometheus"
+
+// Defaults for OTEL_EXPORTER_PROMETHEUS_HOST and OTEL_EXPORTER_PROMETHEUS_PORT
+const (
+	defaultPrometheusHost = "localhost"
+	defaultPrometheusPort = "9464"
+)
+
+// WithMetricReaders replaces the readers selected with environment variables
+func WithMetricReaders(readers ...sdkmetric.Reader) Option {
+	return func(c *Config) {
+		c.MetricReaders = append(c.MetricReaders, readers...)
+	}
+}
+
+// Used to initialise the global OpenTelemetry meter provider,
+// the HTTP server and client instrumentations record their metrics with it
+func InitializeGlobalMeterProvider(ctx context.Context, opts ...Option) (*sdkmetric.MeterProvider, error) {
+	return initializeGlobalMeterProvider(ctx, newConfig(opts...))
+}
+
+func initializeGlobalMeterProvider(ctx context.Context, cfg *Config) (*sdkmetric.MeterProvider, error) {
+	readers := cfg.MetricReaders
+	if len(readers) == 0 {
+		envReaders, err := NewMetricReadersFromEnv(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize metric readers: %w", err)
+		}
+		readers = envReaders
+	}
+
+	resource, err := newResource(ctx, cfg)
+	if err != nil {
+		for _, r := range readers {
+			_ = r.Shutdown(ctx)
+		}
+		return nil, err
+	}
+
+	mpOpts := []sdkmetric.Option{sdkmetric.WithResource(resource)}
+	for _, r := range readers {
+		mpOpts = append(mpOpts, sdkmetric.WithReader(r))
+	}
+
+	mp := sdkmetric.NewMeterProvider(mpOpts...)
+
+	// Register the global Meter provider
+	otel.SetMeterProvider(mp)
+
+	return mp, nil
+}
+
+// NewMetricReadersFromEnv creates a reader for each exporter listed in OTEL_METRICS_EXPORTER,
+// i.e. "otlp,prometheus" pushes the metrics to Honeycomb and exposes them for a local Prometheus.
+// When not set only the OTLP exporter is used, with the protocol from
+// OTEL_EXPORTER_OTLP_METRICS_PROTOCOL or OTEL_EXPORTER_OTLP_PROTOCOL
+func NewMetricReadersFromEnv(ctx context.Context) ([]sdkmetric.Reader, error) {
+	names := os.Getenv("OTEL_METRICS_EXPORTER")
+	if names == "" {
+		names = ExporterOTLP
+	}
+
+	var readers []sdkmetric.Reader
+	for _, name := range strings.Split(names, ",") {
+		reader, err := newMetricReader(ctx, name, signalProtocol("METRICS"))
+		if err != nil {
+			for _, r := range readers {
+				_ = r.Shutdown(ctx)
+			}
+			return nil, err
+		}
+		if reader != nil {
+			readers = append(readers, reader)
+		}
+	}
+
+	return readers, nil
+}
+
+// newMetricReader returns a nil reader for the none exporter
+func newMetricReader(ctx context.Context, name, protocol string) (sdkmetric.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", ExporterOTLP:
+		exp, err := newOTLPMetricExporter(ctx, protocol)
+		if err != nil {
+			return nil, err
+		}
+		// The export interval is read from OTEL_METRIC_EXPORT_INTERVAL, 60 seconds by default
+		return sdkmetric.NewPeriodicReader(exp), nil
+	case ExporterConsole:
+		exp, err := stdoutmetric.New(stdoutmetric.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		return sdkmetric.NewPeriodicReader(exp), nil
+	case ExporterPrometheus:
+		return newPrometheusReader()
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported metrics exporter %q", name)
+	}
+}
+
+func newOTLPMetricExporter(ctx context.Context, protocol string) (sdkmetric.Exporter, error) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "", ProtocolGRPC:
+		return otlpmetricgrpc.New(ctx)
+	case ProtocolHTTPProtobuf:
+		return otlpmetrichttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", protocol)
+	}
+}
+
+// prometheusReader serves the /metrics endpoint for the pull exporter,
+// the HTTP server is closed together with the reader
+type prometheusReader struct {
+	sdkmetric.Reader
+	server *http.Server
+}
+
+func newPrometheusReader() (sdkmetric.Reader, error) {
+	// A dedicated registry keeps the output limited to the OpenTelemetry metrics
+	registry := prometheus.NewRegistry()
+	exp, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, err
+	}
+
+	host := os.Getenv("OTEL_EXPORTER_PROMETHEUS_HOST")
+	if host == "" {
+		host = defaultPrometheusHost
+	}
+	port := os.Getenv("OTEL_EXPORTER_PROMETHEUS_PORT")
+	if port == "" {
+		port = defaultPrometheusPort
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		_ = exp.Shutdown(context.Background())
+		return nil, fmt.Errorf("failed to listen for Prometheus scrapes: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		log.Printf("Serving Prometheus metrics on http://%s/metrics", lis.Addr().String())
+		if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Prometheus metrics server failed: %v", err)
+		}
+	}()
+
+	return &prometheusReader{Reader: exp, server: server}, nil
+}
+
+func (r *prometheusReader) Shutdown(ctx context.Context) error {
+	return errors.Join(r.server.Shutdown(ctx), r.Reader.Shutdown(ctx))
+}
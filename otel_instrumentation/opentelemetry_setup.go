@@ -2,58 +2,307 @@ package otel_instrumentation
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
-
-	_ "github.com/joho/godotenv/autoload"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
-// Used to initialise the global OpenTelemetry trace provider and exporter
-func InitializeGlobalTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, *otlptrace.Exporter, error) {
-	// Configure a new OTLP exporter using environment variables for sending data to Honeycomb over gRPC
-	clientOTel := otlptracegrpc.NewClient()
-	exp, err := otlptrace.New(ctx, clientOTel)
-	if err != nil {
-		log.Fatalf("failed to initialize exporter: %e", err)
+const defaultShutdownTimeout = 5 * time.Second
+
+// Config holds the settings used by InitializeGlobalTracerProvider,
+// every field left empty falls back to the defaults used by the example apps
+type Config struct {
+	// Sampler decides which spans are recorded, defaults to AlwaysSample
+	// or, with EnvDefaults, to the one set in OTEL_TRACES_SAMPLER
+	Sampler sdktrace.Sampler
+	// ResourceAttributes are merged on top of the default or detected resource
+	ResourceAttributes []attribute.KeyValue
+	// Exporter receives the finished spans, defaults to the one selected by OTEL_TRACES_EXPORTER
+	Exporter sdktrace.SpanExporter
+	// Propagators used to carry the context across services, defaults to W3C trace context and baggage
+	Propagators []propagation.TextMapPropagator
+	// BatchOptions tune the batch span processor wrapping the exporter
+	BatchOptions []sdktrace.BatchSpanProcessorOption
+	// TailSampling enables the TailSamplingProcessor in front of the batcher,
+	// with EnvDefaults it defaults to the config set with the OTEL_TAIL_SAMPLING_* variables
+	TailSampling *TailSamplingConfig
+	// MetricReaders collect the metrics, defaults to the ones selected by OTEL_METRICS_EXPORTER
+	MetricReaders []sdkmetric.Reader
+	// LogExporter receives the log records, defaults to the one selected by OTEL_LOGS_EXPORTER
+	LogExporter sdklog.Exporter
+	// ShutdownTimeout bounds the time spent flushing telemetry in the shutdown func returned by Setup
+	ShutdownTimeout time.Duration
+	// NoopFallback makes Setup install a no-op tracer provider instead of failing
+	// when the OTLP collector can't be reached, defaults to OTEL_NOOP_FALLBACK=true
+	NoopFallback bool
+	// EnvDefaults reads the sampler and the tail sampling not passed as options from the
+	// OTEL_* variables and detects the resource, otherwise every span is sampled and the
+	// resource is the SDK default with environment=test, like the first version
+	EnvDefaults bool
+
+	// detected once and shared by all the providers
+	resource *resource.Resource
+}
+
+// Option changes a single setting of the Config
+type Option func(*Config)
+
+// WithSampler replaces the sampler set with OTEL_TRACES_SAMPLER
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(c *Config) {
+		c.Sampler = sampler
 	}
+}
 
-	resource, rErr := resource.Merge(
-		resource.Default(),
-		resource.NewWithAttributes(
-			semconv.SchemaURL,
-			attribute.String("environment", "test"),
-		),
-	)
+// WithResourceAttributes adds attributes to the resource attached to every span,
+// they take precedence over the detected ones with the same key
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *Config) {
+		c.ResourceAttributes = append(c.ResourceAttributes, attrs...)
+	}
+}
+
+// WithExporter replaces the exporter selected with environment variables
+func WithExporter(exp sdktrace.SpanExporter) Option {
+	return func(c *Config) {
+		c.Exporter = exp
+	}
+}
+
+// WithPropagators replaces the default W3C trace context and baggage propagators
+func WithPropagators(propagators ...propagation.TextMapPropagator) Option {
+	return func(c *Config) {
+		c.Propagators = propagators
+	}
+}
+
+// WithBatchOptions passes options to the batch span processor, i.e. batch size or timeout
+func WithBatchOptions(opts ...sdktrace.BatchSpanProcessorOption) Option {
+	return func(c *Config) {
+		c.BatchOptions = append(c.BatchOptions, opts...)
+	}
+}
+
+// WithShutdownTimeout changes the time allowed to flush telemetry on shutdown, 5 seconds by default
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.ShutdownTimeout = timeout
+	}
+}
+
+// WithNoopFallback enables or disables the degraded mode where, if the collector
+// is unreachable, the app keeps running without exporting any telemetry
+func WithNoopFallback(enabled bool) Option {
+	return func(c *Config) {
+		c.NoopFallback = enabled
+	}
+}
+
+// WithEnvDefaults takes the settings not passed as options from the environment,
+// see Config.EnvDefaults, it is always applied by Setup
+func WithEnvDefaults() Option {
+	return func(c *Config) {
+		c.EnvDefaults = true
+	}
+}
+
+func newConfig(opts ...Option) *Config {
+	cfg := &Config{
+		ShutdownTimeout: defaultShutdownTimeout,
+		NoopFallback:    strings.EqualFold(os.Getenv("OTEL_NOOP_FALLBACK"), "true"),
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if len(cfg.Propagators) == 0 {
+		cfg.Propagators = []propagation.TextMapPropagator{
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		}
+	}
+
+	return cfg
+}
+
+// Used to initialise the global OpenTelemetry trace provider and exporter.
+// Without options every span is sampled and exported, by default with OTLP over gRPC,
+// pass WithEnvDefaults to read the sampler and the tail sampling from the environment.
+func InitializeGlobalTracerProvider(ctx context.Context, opts ...Option) (*sdktrace.TracerProvider, sdktrace.SpanExporter, error) {
+	return initializeGlobalTracerProvider(ctx, newConfig(opts...))
+}
+
+func initializeGlobalTracerProvider(ctx context.Context, cfg *Config) (*sdktrace.TracerProvider, sdktrace.SpanExporter, error) {
+	// The sampling settings are read first, so that all the invalid ones are reported together
+	var errs []error
+
+	sampler := cfg.Sampler
+	if sampler == nil && !cfg.EnvDefaults {
+		sampler = sdktrace.AlwaysSample()
+	}
+	if sampler == nil {
+		envSampler, err := SamplerFromEnv()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to initialize sampler: %w", err))
+		}
+		sampler = envSampler
+	}
 
-	if rErr != nil {
-		panic(rErr)
+	tailSampling := cfg.TailSampling
+	if tailSampling == nil && cfg.EnvDefaults {
+		envTailSampling, err := TailSamplingConfigFromEnv()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to initialize tail sampling: %w", err))
+		}
+		tailSampling = envTailSampling
 	}
 
-	// Create a new tracer provider with a batch span processor and the otlp exporter
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	exp := cfg.Exporter
+	if exp == nil {
+		// Configure the exporter selected with environment variables,
+		// by default OTLP over gRPC for sending data to Honeycomb
+		envExp, err := NewSpanExporterFromEnv(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize exporter: %w", err)
+		}
+		exp = envExp
+	}
+
+	resource, err := newResource(ctx, cfg)
+	if err != nil {
+		// the exporter is not owned by a provider yet, so it has to be released here
+		_ = exp.Shutdown(ctx)
+		return nil, nil, err
+	}
+
+	// The spans only recorded by the sampler are exported when they end with an error
+	var processor sdktrace.SpanProcessor = &recordedErrorsProcessor{
+		SpanProcessor: sdktrace.NewBatchSpanProcessor(exp, cfg.BatchOptions...),
+	}
+	if tailSampling != nil {
+		processor = NewTailSamplingProcessor(processor, *tailSampling)
+	}
+
+	// Create a new tracer provider with the span processor and the configured exporter
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exp),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(parentSampleRateSampler{Sampler: sampler}),
 		sdktrace.WithResource(resource),
 	)
 
 	// Register the global Tracer provider
 	otel.SetTracerProvider(tp)
 
-	// Register the W3C trace context and baggage propagators so data is propagated across services/processes
+	setGlobalPropagators(cfg)
+
+	return tp, exp, nil
+}
+
+// Register the propagators so data is propagated across services/processes
+func setGlobalPropagators(cfg *Config) {
 	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
-		),
+		propagation.NewCompositeTextMapPropagator(cfg.Propagators...),
 	)
+}
 
-	return tp, exp, nil
+// Setup initialises the global traces, metrics and logs and returns a single func that flushes
+// and releases everything, bounded by the configured shutdown timeout.
+// If the noop fallback is enabled and the OTLP collector can't be reached
+// the apps keep running with a no-op tracer provider.
+func Setup(ctx context.Context, opts ...Option) (func(context.Context) error, error) {
+	cfg := newConfig(append([]Option{WithEnvDefaults()}, opts...)...)
+
+	if cfg.NoopFallback && cfg.Exporter == nil && UsesOTLPExporter() {
+		if err := CheckCollector(ctx); err != nil {
+			log.Printf("OpenTelemetry collector unreachable, telemetry disabled: %v", err)
+			return setupNoop(cfg), nil
+		}
+	}
+
+	shutdown, err := setupProviders(ctx, cfg)
+	if err != nil {
+		if cfg.NoopFallback {
+			log.Printf("OpenTelemetry setup failed, telemetry disabled: %v", err)
+			return setupNoop(cfg), nil
+		}
+		return nil, err
+	}
+
+	return shutdown, nil
+}
+
+func setupProviders(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	tp, _, err := initializeGlobalTracerProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mp, err := initializeGlobalMeterProvider(ctx, cfg)
+	if err != nil {
+		_ = tp.Shutdown(ctx)
+		return nil, err
+	}
+
+	lp, err := initializeGlobalLoggerProvider(ctx, cfg)
+	if err != nil {
+		_ = tp.Shutdown(ctx)
+		_ = mp.Shutdown(ctx)
+		return nil, err
+	}
+
+	// slog.Default and the log package now write to stderr and to the logger provider
+	slog.SetDefault(NewLogger(loggerName))
+
+	shutdown := func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+		defer cancel()
+
+		var errs []error
+
+		// Shutting down the provider flushes the batcher and then shuts down the exporter
+		if err := tp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown tracer provider: %w", err))
+		}
+
+		// The last collection is exported before closing the readers
+		if err := mp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown meter provider: %w", err))
+		}
+
+		if err := lp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown logger provider: %w", err))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	return shutdown, nil
+}
+
+func setupNoop(cfg *Config) func(context.Context) error {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	otel.SetMeterProvider(metricnoop.NewMeterProvider())
+	slog.SetDefault(slog.New(newConsoleHandler()))
+	// the incoming context is still propagated to the downstream services
+	setGlobalPropagators(cfg)
+	return func(context.Context) error { return nil }
 }
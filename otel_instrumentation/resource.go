@@ -0,0 +1,157 @@
+package otel_instrumentation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Version of the app reported as service.version, set at build time with
+//
+//	go build -ldflags "-X github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation.Version=1.2.3"
+//
+// when empty the version of the main module or the VCS revision from the build info is used
+var Version string
+
+// Environment reported when DEPLOYMENT_ENVIRONMENT is not set
+const defaultDeploymentEnvironment = "test"
+
+// Env vars usually populated with the Kubernetes downward API, mapped to the semantic conventions
+var k8sEnvAttributes = map[string]func(string) attribute.KeyValue{
+	"K8S_POD_NAME":        semconv.K8SPodName,
+	"K8S_POD_UID":         semconv.K8SPodUID,
+	"K8S_NAMESPACE_NAME":  semconv.K8SNamespaceName,
+	"K8S_NODE_NAME":       semconv.K8SNodeName,
+	"K8S_DEPLOYMENT_NAME": semconv.K8SDeploymentName,
+	"K8S_CONTAINER_NAME":  semconv.K8SContainerName,
+}
+
+// newResource describes the app emitting the telemetry, shared by all the providers.
+// Without EnvDefaults it is the SDK default with environment=test.
+// The detected attributes are overridden by OTEL_RESOURCE_ATTRIBUTES, OTEL_SERVICE_NAME
+// and then by the attributes passed with WithResourceAttributes.
+func newResource(ctx context.Context, cfg *Config) (*resource.Resource, error) {
+	if cfg.resource != nil {
+		return cfg.resource, nil
+	}
+
+	if !cfg.EnvDefaults {
+		// the resource of the first version, kept for the calls without WithEnvDefaults
+		res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+			append([]attribute.KeyValue{attribute.String("environment", defaultDeploymentEnvironment)}, cfg.ResourceAttributes...)...,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create resource: %w", err)
+		}
+		cfg.resource = res
+		return res, nil
+	}
+
+	detected, err := resource.New(ctx,
+		resource.WithHost(),
+		resource.WithOS(),
+		// the command line arguments are left out as they might contain secrets
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessExecutablePath(),
+		resource.WithProcessOwner(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithProcessRuntimeDescription(),
+		resource.WithContainer(),
+		resource.WithDetectors(buildInfoDetector{}, k8sDetector{}, deploymentDetector{}),
+		resource.WithFromEnv(),
+	)
+	if errors.Is(err, resource.ErrPartialResource) {
+		// i.e. the container id is not available outside of a container
+		log.Printf("some resource attributes could not be detected: %v", err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to detect resource: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), detected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	res, err = resource.Merge(res, resource.NewWithAttributes(semconv.SchemaURL, cfg.ResourceAttributes...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	cfg.resource = res
+	return res, nil
+}
+
+// buildInfoDetector sets service.version from the ldflags or from the build info
+type buildInfoDetector struct{}
+
+func (buildInfoDetector) Detect(context.Context) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{}
+
+	version := Version
+	if info, ok := debug.ReadBuildInfo(); ok {
+		revision := ""
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				revision = setting.Value
+				attrs = append(attrs, attribute.String("vcs.revision", revision))
+			}
+		}
+
+		if version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		if version == "" && len(revision) >= 12 {
+			version = revision[:12]
+		}
+	}
+
+	if version != "" {
+		attrs = append(attrs, semconv.ServiceVersion(version))
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
+
+// k8sDetector reads the pod metadata exposed as env vars, i.e. in the pod spec
+//
+//	env:
+//	  - name: K8S_POD_NAME
+//	    valueFrom:
+//	      fieldRef:
+//	        fieldPath: metadata.name
+type k8sDetector struct{}
+
+func (k8sDetector) Detect(context.Context) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{}
+	for env, attr := range k8sEnvAttributes {
+		if value := os.Getenv(env); value != "" {
+			attrs = append(attrs, attr(value))
+		}
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
+
+// deploymentDetector sets deployment.environment from DEPLOYMENT_ENVIRONMENT,
+// the environment attribute is kept for the existing Honeycomb queries
+type deploymentDetector struct{}
+
+func (deploymentDetector) Detect(context.Context) (*resource.Resource, error) {
+	env := os.Getenv("DEPLOYMENT_ENVIRONMENT")
+	if env == "" {
+		env = defaultDeploymentEnvironment
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.DeploymentEnvironment(env),
+		attribute.String("environment", env),
+	), nil
+}
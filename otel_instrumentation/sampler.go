@@ -0,0 +1,322 @@
+package otel_instrumentation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Values accepted by OTEL_TRACES_SAMPLER
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// Attributes checked, in order, to find the route of a span when it starts
+var routeAttributeKeys = []attribute.Key{"http.route", "url.path"}
+
+// The route sampler loaded from the environment, used by NeverSampled
+var activeRouteSampler atomic.Pointer[RouteSampler]
+
+// SamplerFromEnv creates the sampler set with OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG,
+// when not set every span is sampled.
+// If OTEL_TRACES_SAMPLER_RULES points to a rules file the routes matching a rule
+// are sampled with its ratio, the others with the sampler above.
+func SamplerFromEnv() (sdktrace.Sampler, error) {
+	sampler, err := samplerFromName(os.Getenv("OTEL_TRACES_SAMPLER"), os.Getenv("OTEL_TRACES_SAMPLER_ARG"))
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSpace(os.Getenv("OTEL_TRACES_SAMPLER_RULES"))
+	if path == "" {
+		return sampler, nil
+	}
+
+	rules, err := LoadSamplingRules(path)
+	if err != nil {
+		return nil, err
+	}
+
+	routeSampler := NewRouteSampler(rules, sampler)
+	activeRouteSampler.Store(routeSampler)
+	return routeSampler, nil
+}
+
+func samplerFromName(name, arg string) (sdktrace.Sampler, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "", SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		ratio, err := parseRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+		return newRatioSampler(ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		ratio, err := parseRatio(arg)
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.ParentBased(newRatioSampler(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unsupported traces sampler %q", name)
+	}
+}
+
+// parseRatio defaults to 1.0 when the argument is missing, like the other OpenTelemetry SDKs
+func parseRatio(arg string) (float64, error) {
+	if arg == "" {
+		return 1, nil
+	}
+
+	ratio, err := strconv.ParseFloat(arg, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 0, fmt.Errorf("invalid sampler ratio %q, expected a number between 0 and 1", arg)
+	}
+	return ratio, nil
+}
+
+// Key of the tracestate entry carrying the SampleRate of the head sampler to the child spans,
+// in this app and in the services called
+const sampleRateTraceStateKey = "samplerate"
+
+// ratioSampler samples like TraceIDRatioBased and sets the SampleRate of the ratio on the
+// sampled spans and in their tracestate, the TailSamplingProcessor multiplies its own rate by it
+type ratioSampler struct {
+	sdktrace.Sampler
+	rate int
+}
+
+func newRatioSampler(ratio float64) sdktrace.Sampler {
+	sampler := sdktrace.TraceIDRatioBased(ratio)
+	if ratio <= 0 || ratio >= 1 {
+		return sampler
+	}
+	return ratioSampler{Sampler: sampler, rate: int(math.Round(1 / ratio))}
+}
+
+func (s ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.Sampler.ShouldSample(p)
+	if result.Decision == sdktrace.RecordAndSample {
+		result.Attributes = append(result.Attributes, SampleRateKey.Int(s.rate))
+		if ts, err := result.Tracestate.Insert(sampleRateTraceStateKey, strconv.Itoa(s.rate)); err == nil {
+			result.Tracestate = ts
+		}
+	}
+	return result
+}
+
+// parentSampleRateSampler sets the SampleRate found in the tracestate of the parent on the
+// spans it samples, so that the child spans, local or remote, are weighted like their root
+type parentSampleRateSampler struct {
+	sdktrace.Sampler
+}
+
+func (s parentSampleRateSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.Sampler.ShouldSample(p)
+	if result.Decision != sdktrace.RecordAndSample || slices.ContainsFunc(result.Attributes, isSampleRate) {
+		return result
+	}
+
+	rate, err := strconv.Atoi(result.Tracestate.Get(sampleRateTraceStateKey))
+	if err == nil && rate > 1 {
+		result.Attributes = append(result.Attributes, SampleRateKey.Int(rate))
+	}
+	return result
+}
+
+func isSampleRate(attr attribute.KeyValue) bool {
+	return attr.Key == SampleRateKey
+}
+
+// SamplingRules configures the RouteSampler, i.e.
+//
+//	{
+//	  "always_sample_errors": true,
+//	  "rules": [
+//	    {"route": "/health", "ratio": 0},
+//	    {"route": "/hello-resty", "ratio": 0.1}
+//	  ]
+//	}
+type SamplingRules struct {
+	// AlwaysSampleErrors exports the spans ending with an error status
+	// even when their trace was not sampled
+	AlwaysSampleErrors bool `json:"always_sample_errors"`
+	// Rules are evaluated in order, the first matching the route is used
+	Rules []RouteRule `json:"rules"`
+}
+
+// RouteRule sets the ratio of traces sampled for a route,
+// a route ending with * matches all the routes with that prefix
+type RouteRule struct {
+	Route string  `json:"route"`
+	Ratio float64 `json:"ratio"`
+}
+
+func (r RouteRule) matches(route string) bool {
+	if prefix, ok := strings.CutSuffix(r.Route, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	return r.Route == route
+}
+
+// LoadSamplingRules reads the rules from a JSON file
+func LoadSamplingRules(path string) (SamplingRules, error) {
+	var rules SamplingRules
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("failed to read sampling rules: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("failed to parse sampling rules %s: %w", path, err)
+	}
+
+	for _, rule := range rules.Rules {
+		if rule.Ratio < 0 || rule.Ratio > 1 {
+			return rules, fmt.Errorf("invalid ratio %v for route %s, expected a number between 0 and 1", rule.Ratio, rule.Route)
+		}
+	}
+
+	return rules, nil
+}
+
+// RouteSampler samples the root spans with the ratio of the first rule matching
+// their route or with the fallback sampler, the other spans follow the decision of their parent.
+// To always export the errors the dropped spans are still recorded,
+// so that the ones ending with an error can be exported when they end: without
+// their parents, unless the TailSamplingProcessor keeps the whole trace.
+type RouteSampler struct {
+	rules    SamplingRules
+	samplers []sdktrace.Sampler
+	fallback sdktrace.Sampler
+}
+
+// NewRouteSampler creates a sampler from the rules, the routes without a rule use the fallback
+func NewRouteSampler(rules SamplingRules, fallback sdktrace.Sampler) *RouteSampler {
+	s := &RouteSampler{
+		rules:    rules,
+		fallback: fallback,
+	}
+	for _, rule := range rules.Rules {
+		s.samplers = append(s.samplers, newRatioSampler(rule.Ratio))
+	}
+	return s
+}
+
+func (s *RouteSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+
+	var result sdktrace.SamplingResult
+	if psc.IsValid() {
+		result = sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+		if psc.IsSampled() {
+			result.Decision = sdktrace.RecordAndSample
+		}
+	} else {
+		// the ratio samplers add the SampleRate to the tracestate of the root
+		result = s.samplerFor(spanRoute(p)).ShouldSample(p)
+	}
+
+	if result.Decision == sdktrace.Drop && s.rules.AlwaysSampleErrors {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s *RouteSampler) Description() string {
+	return fmt.Sprintf("RouteSampler{rules:%d,errors:%t,fallback:%s}", len(s.rules.Rules), s.rules.AlwaysSampleErrors, s.fallback.Description())
+}
+
+// Never reports if the route is never sampled, so no span has to be created for it
+func (s *RouteSampler) Never(route string) bool {
+	for _, rule := range s.rules.Rules {
+		if rule.matches(route) {
+			return rule.Ratio == 0 && !s.rules.AlwaysSampleErrors
+		}
+	}
+	return false
+}
+
+func (s *RouteSampler) samplerFor(route string) sdktrace.Sampler {
+	for i, rule := range s.rules.Rules {
+		if rule.matches(route) {
+			return s.samplers[i]
+		}
+	}
+	return s.fallback
+}
+
+// spanRoute returns the route from the start attributes or, if missing, the span name
+// that otelfiber sets to the request path
+func spanRoute(p sdktrace.SamplingParameters) string {
+	for _, key := range routeAttributeKeys {
+		for _, attr := range p.Attributes {
+			if attr.Key == key {
+				return attr.Value.AsString()
+			}
+		}
+	}
+	return p.Name
+}
+
+// NeverSampled reports if the route is never sampled by the rules set
+// in OTEL_TRACES_SAMPLER_RULES, it can be used in otelfiber.WithNext to skip creating the span
+func NeverSampled(route string) bool {
+	if s := activeRouteSampler.Load(); s != nil {
+		return s.Never(route)
+	}
+	return false
+}
+
+// recordedErrorsProcessor forwards to the next processor the sampled spans and
+// the ones only recorded by the sampler that ended with an error
+type recordedErrorsProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+func (p *recordedErrorsProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+
+	if s.Status().Code == codes.Error {
+		p.SpanProcessor.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+// sampledSpan flags a recorded span as sampled, so that it is exported
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
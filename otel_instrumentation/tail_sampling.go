@@ -0,0 +1,425 @@
+package otel_instrumentation
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Instrumentation scope of the metrics recorded by this package
+const meterName = "github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+
+// Defaults used for the zero fields of TailSamplingConfig
+const (
+	defaultTailMaxTraces        = 10000
+	defaultTailMaxSpansPerTrace = 1000
+	defaultTailMaxTraceAge      = time.Minute
+	defaultTailLatencyThreshold = time.Second
+)
+
+// Reasons recorded in the dropped traces metric
+const (
+	dropReasonPolicy     = "policy"
+	dropReasonDynamic    = "dynamic_sampler"
+	dropReasonBufferFull = "buffer_full"
+	dropReasonExpired    = "expired"
+	dropReasonShutdown   = "shutdown"
+	dropReasonSpanLimit  = "span_limit"
+)
+
+// TailSamplingConfig sets when a trace is kept by the TailSamplingProcessor,
+// a trace is kept if any of the conditions is met
+type TailSamplingConfig struct {
+	// LatencyThreshold keeps the traces whose local root span lasted at least this long, 0 disables it
+	LatencyThreshold time.Duration
+	// KeepErrors keeps the traces with at least a span ending with an error
+	KeepErrors bool
+	// Attributes keeps the traces with at least a span having one of these attributes
+	Attributes []attribute.KeyValue
+	// MaxTraces bounds the number of traces waiting for a decision, the oldest one is dropped when full
+	MaxTraces int
+	// MaxSpansPerTrace bounds the spans buffered for a trace, the following ones but the local root are discarded
+	MaxSpansPerTrace int
+	// MaxTraceAge drops the traces whose root span didn't end in time
+	MaxTraceAge time.Duration
+	// DynamicSampler, if set, samples the traces not matching the conditions above
+	// instead of dropping them, the kept traces get its SampleRate times the one of the head sampler
+	DynamicSampler *DynamicSampler
+}
+
+// WithTailSampling buffers the spans of each trace and only exports the traces matching the config
+func WithTailSampling(cfg TailSamplingConfig) Option {
+	return func(c *Config) {
+		c.TailSampling = &cfg
+	}
+}
+
+// TailSamplingConfigFromEnv returns the config set with the OTEL_TAIL_SAMPLING_* variables,
+// or nil if OTEL_TAIL_SAMPLING_ENABLED is not true:
+//   - OTEL_TAIL_SAMPLING_LATENCY_THRESHOLD: i.e. 500ms, 1s by default
+//   - OTEL_TAIL_SAMPLING_KEEP_ERRORS: true by default
+//   - OTEL_TAIL_SAMPLING_ATTRIBUTES: comma separated key=value pairs
+//   - OTEL_TAIL_SAMPLING_MAX_TRACES: 10000 by default
+//   - OTEL_TAIL_SAMPLING_GOAL_THROUGHPUT: traces per second kept by the DynamicSampler, disabled by default
+//   - OTEL_TAIL_SAMPLING_ADJUST_INTERVAL: how often the DynamicSampler rates change, 30s by default
+//
+// The invalid values are all reported in the returned error.
+func TailSamplingConfigFromEnv() (*TailSamplingConfig, error) {
+	if !strings.EqualFold(os.Getenv("OTEL_TAIL_SAMPLING_ENABLED"), "true") {
+		return nil, nil
+	}
+
+	cfg := &TailSamplingConfig{
+		LatencyThreshold: defaultTailLatencyThreshold,
+		KeepErrors:       !strings.EqualFold(os.Getenv("OTEL_TAIL_SAMPLING_KEEP_ERRORS"), "false"),
+	}
+
+	var errs []error
+
+	if value := strings.TrimSpace(os.Getenv("OTEL_TAIL_SAMPLING_LATENCY_THRESHOLD")); value != "" {
+		threshold, err := time.ParseDuration(value)
+		if err != nil || threshold <= 0 {
+			errs = append(errs, fmt.Errorf("invalid OTEL_TAIL_SAMPLING_LATENCY_THRESHOLD %q, expected a positive duration, i.e. 500ms", value))
+		} else {
+			cfg.LatencyThreshold = threshold
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv("OTEL_TAIL_SAMPLING_MAX_TRACES")); value != "" {
+		maxTraces, err := strconv.Atoi(value)
+		if err != nil || maxTraces <= 0 {
+			errs = append(errs, fmt.Errorf("invalid OTEL_TAIL_SAMPLING_MAX_TRACES %q, expected a positive number", value))
+		} else {
+			cfg.MaxTraces = maxTraces
+		}
+	}
+
+	for _, pair := range strings.Split(os.Getenv("OTEL_TAIL_SAMPLING_ATTRIBUTES"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			errs = append(errs, fmt.Errorf("invalid OTEL_TAIL_SAMPLING_ATTRIBUTES pair %q, expected key=value", pair))
+			continue
+		}
+		cfg.Attributes = append(cfg.Attributes, attribute.String(key, value))
+	}
+
+	var interval time.Duration
+	if value := strings.TrimSpace(os.Getenv("OTEL_TAIL_SAMPLING_ADJUST_INTERVAL")); value != "" {
+		var err error
+		interval, err = time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			errs = append(errs, fmt.Errorf("invalid OTEL_TAIL_SAMPLING_ADJUST_INTERVAL %q, expected a positive duration, i.e. 30s", value))
+		}
+	}
+
+	if value := strings.TrimSpace(os.Getenv("OTEL_TAIL_SAMPLING_GOAL_THROUGHPUT")); value != "" {
+		goal, err := strconv.ParseFloat(value, 64)
+		if err != nil || goal <= 0 {
+			errs = append(errs, fmt.Errorf("invalid OTEL_TAIL_SAMPLING_GOAL_THROUGHPUT %q, expected a positive number of traces per second", value))
+		} else {
+			cfg.DynamicSampler = NewDynamicSampler(DynamicSamplerConfig{
+				GoalThroughputPerSec: goal,
+				AdjustInterval:       interval,
+			})
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// TailSamplingProcessor buffers the spans per trace and, when the local root span ends,
+// decides whether to forward the whole trace to the next processor (usually the batcher).
+// Spans not sampled by the head sampler are forwarded untouched, unless KeepErrors is set:
+// then the traces only recorded are kept whole if a span ended with an error.
+// The decision is not propagated: every service using it decides on its own part of the trace,
+// so it should only be enabled in the edge service, the one receiving the requests from outside,
+// the services it calls then export every sampled span, kept or not by the edge.
+type TailSamplingProcessor struct {
+	next sdktrace.SpanProcessor
+	cfg  TailSamplingConfig
+
+	mu     sync.Mutex
+	traces map[trace.TraceID]*list.Element
+	// pending traces ordered by arrival of their first span, the oldest at the front
+	order *list.List
+	// recent decisions, the sample rate of the kept traces or 0 for the dropped ones,
+	// so that the spans ending after their root follow the same outcome
+	decided      map[trace.TraceID]int
+	decidedOrder *list.List
+
+	keptCounter    metric.Int64Counter
+	droppedCounter metric.Int64Counter
+}
+
+type pendingTrace struct {
+	id      trace.TraceID
+	created time.Time
+	spans   []sdktrace.ReadOnlySpan
+}
+
+// NewTailSamplingProcessor wraps the next processor, the zero fields of the config use the defaults
+func NewTailSamplingProcessor(next sdktrace.SpanProcessor, cfg TailSamplingConfig) *TailSamplingProcessor {
+	if cfg.MaxTraces <= 0 {
+		cfg.MaxTraces = defaultTailMaxTraces
+	}
+	if cfg.MaxSpansPerTrace <= 0 {
+		cfg.MaxSpansPerTrace = defaultTailMaxSpansPerTrace
+	}
+	if cfg.MaxTraceAge <= 0 {
+		cfg.MaxTraceAge = defaultTailMaxTraceAge
+	}
+
+	meter := otel.Meter(meterName)
+	kept, err := meter.Int64Counter("tail_sampling.traces.kept",
+		metric.WithDescription("Traces exported by the tail sampling processor"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	dropped, err := meter.Int64Counter("tail_sampling.traces.dropped",
+		metric.WithDescription("Traces discarded by the tail sampling processor, by reason"))
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &TailSamplingProcessor{
+		next:           next,
+		cfg:            cfg,
+		traces:         make(map[trace.TraceID]*list.Element),
+		order:          list.New(),
+		decided:        make(map[trace.TraceID]int),
+		decidedOrder:   list.New(),
+		keptCounter:    kept,
+		droppedCounter: dropped,
+	}
+}
+
+func (p *TailSamplingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *TailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	// The spans only recorded by the head sampler, i.e. with always_sample_errors, are buffered
+	// too when keeping the errors, so that a trace with an error is exported whole
+	if !s.SpanContext().IsSampled() && !p.cfg.KeepErrors {
+		p.next.OnEnd(s)
+		return
+	}
+
+	forward, dropped := p.buffer(s)
+
+	for _, reason := range dropped {
+		p.droppedCounter.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", reason)))
+	}
+
+	for _, span := range forward {
+		p.next.OnEnd(span)
+	}
+}
+
+// buffer adds the span to its trace and returns the spans to forward
+// and the reasons of the traces dropped in the meantime
+func (p *TailSamplingProcessor) buffer(s sdktrace.ReadOnlySpan) ([]sdktrace.ReadOnlySpan, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	traceID := s.SpanContext().TraceID()
+	dropped := p.expire(time.Now())
+
+	if rate, ok := p.decided[traceID]; ok {
+		if rate > 0 {
+			return exported([]sdktrace.ReadOnlySpan{s}, rate), dropped
+		}
+		return nil, dropped
+	}
+
+	elem, ok := p.traces[traceID]
+	if !ok {
+		if p.order.Len() >= p.cfg.MaxTraces {
+			p.remove(p.order.Front())
+			dropped = append(dropped, dropReasonBufferFull)
+		}
+		elem = p.order.PushBack(&pendingTrace{id: traceID, created: time.Now()})
+		p.traces[traceID] = elem
+	}
+
+	// The local root is the span without a parent or with a parent in another service
+	localRoot := !s.Parent().IsValid() || s.Parent().IsRemote()
+
+	pending := elem.Value.(*pendingTrace)
+	if localRoot || len(pending.spans) < p.cfg.MaxSpansPerTrace {
+		pending.spans = append(pending.spans, s)
+	} else {
+		// counted per span, the rest of the trace is still exported if kept
+		dropped = append(dropped, dropReasonSpanLimit)
+	}
+
+	if !localRoot {
+		return nil, dropped
+	}
+
+	p.remove(elem)
+
+	rate, reason := p.decide(s, pending.spans)
+	p.remember(traceID, rate)
+
+	if rate == 0 {
+		if reason != "" {
+			dropped = append(dropped, reason)
+		}
+		return nil, dropped
+	}
+
+	p.keptCounter.Add(context.Background(), 1)
+	return exported(pending.spans, rate), dropped
+}
+
+// decide returns the sample rate of the trace, 0 and the reason if it is dropped.
+// The rate includes the one of the head sampler, i.e. 20 for a trace kept once every 2
+// by the DynamicSampler after a head sampling ratio of 0.1.
+func (p *TailSamplingProcessor) decide(root sdktrace.ReadOnlySpan, spans []sdktrace.ReadOnlySpan) (int, string) {
+	// a trace dropped by the head sampler is only kept for its errors,
+	// it is not counted as dropped as it wouldn't be exported anyway
+	if !root.SpanContext().IsSampled() {
+		if hasError(spans) {
+			return 1, ""
+		}
+		return 0, ""
+	}
+
+	headRate := headSampleRate(root)
+
+	if p.shouldKeep(root, spans) {
+		return headRate, ""
+	}
+
+	if p.cfg.DynamicSampler == nil {
+		return 0, dropReasonPolicy
+	}
+
+	if keep, rate := p.cfg.DynamicSampler.Sample(root); keep {
+		return rate * headRate, ""
+	}
+	return 0, dropReasonDynamic
+}
+
+// exported stamps the rate on all the spans of the kept traces and flags
+// as sampled the ones only recorded by the head sampler
+func exported(spans []sdktrace.ReadOnlySpan, rate int) []sdktrace.ReadOnlySpan {
+	stamped := make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	for _, span := range spans {
+		if !span.SpanContext().IsSampled() {
+			span = sampledSpan{ReadOnlySpan: span}
+		}
+		if rate > 1 {
+			span = sampleRateSpan{ReadOnlySpan: span, rate: rate}
+		}
+		stamped = append(stamped, span)
+	}
+	return stamped
+}
+
+func (p *TailSamplingProcessor) shouldKeep(root sdktrace.ReadOnlySpan, spans []sdktrace.ReadOnlySpan) bool {
+	if p.cfg.LatencyThreshold > 0 && root.EndTime().Sub(root.StartTime()) >= p.cfg.LatencyThreshold {
+		return true
+	}
+
+	if p.cfg.KeepErrors && hasError(spans) {
+		return true
+	}
+
+	for _, span := range spans {
+		if hasAnyAttribute(span, p.cfg.Attributes) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasError(spans []sdktrace.ReadOnlySpan) bool {
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyAttribute(span sdktrace.ReadOnlySpan, wanted []attribute.KeyValue) bool {
+	for _, want := range wanted {
+		for _, attr := range span.Attributes() {
+			if attr.Key == want.Key && attr.Value.Emit() == want.Value.Emit() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expire drops the traces waiting longer than MaxTraceAge, must be called holding the lock
+func (p *TailSamplingProcessor) expire(now time.Time) []string {
+	var dropped []string
+	for elem := p.order.Front(); elem != nil; elem = p.order.Front() {
+		if now.Sub(elem.Value.(*pendingTrace).created) < p.cfg.MaxTraceAge {
+			break
+		}
+		p.remove(elem)
+		dropped = append(dropped, dropReasonExpired)
+	}
+	return dropped
+}
+
+func (p *TailSamplingProcessor) remove(elem *list.Element) {
+	p.order.Remove(elem)
+	delete(p.traces, elem.Value.(*pendingTrace).id)
+}
+
+// remember keeps as many decisions as the traces that can be buffered
+func (p *TailSamplingProcessor) remember(traceID trace.TraceID, rate int) {
+	if p.decidedOrder.Len() >= p.cfg.MaxTraces {
+		oldest := p.decidedOrder.Front()
+		p.decidedOrder.Remove(oldest)
+		delete(p.decided, oldest.Value.(trace.TraceID))
+	}
+	p.decided[traceID] = rate
+	p.decidedOrder.PushBack(traceID)
+}
+
+// Shutdown drops the traces still waiting for their root span and shuts down the next processor
+func (p *TailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	pending := p.order.Len()
+	p.order.Init()
+	p.traces = make(map[trace.TraceID]*list.Element)
+	p.mu.Unlock()
+
+	if pending > 0 {
+		p.droppedCounter.Add(ctx, int64(pending), metric.WithAttributes(attribute.String("reason", dropReasonShutdown)))
+	}
+
+	return p.next.Shutdown(ctx)
+}
+
+func (p *TailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
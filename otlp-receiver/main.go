@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/otlpreceiver"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+)
+
+func main() {
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
+
+	cfg, err := config.Load(config.WithSections(config.OTLPReceiverSection))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var store otlpreceiver.Store = otlpreceiver.NewMemoryStore(cfg.OTLPReceiver.MaxTraces)
+	if cfg.OTLPReceiver.StorageDir != "" {
+		store, err = otlpreceiver.NewDiskStore(cfg.OTLPReceiver.StorageDir, cfg.OTLPReceiver.MaxTraces)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// unlike the other apps it's not traced, it would receive its own spans
+	receiver := otlpreceiver.New(otlpreceiver.WithStore(store))
+	if err := receiver.Run(ctx, cfg.OTLPReceiverGRPCAddress(), cfg.OTLPReceiverHTTPAddress()); err != nil {
+		log.Fatal(err)
+	}
+}
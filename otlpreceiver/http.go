@@ -0,0 +1,185 @@
+package otlpreceiver
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of OTLP over HTTP
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// Largest body accepted, the exporters send batches of at most 512 spans by default
+const maxBodySize = 32 << 20
+
+// Handler serves OTLP over HTTP on /v1/traces, /v1/metrics and /v1/logs, the trace viewer on /
+// and the JSON API on /api/traces
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/traces", r.handleTraces)
+	mux.HandleFunc("POST /v1/metrics", discard(&colmetricspb.ExportMetricsServiceRequest{}, &colmetricspb.ExportMetricsServiceResponse{}))
+	mux.HandleFunc("POST /v1/logs", discard(&collogspb.ExportLogsServiceRequest{}, &collogspb.ExportLogsServiceResponse{}))
+
+	mux.HandleFunc("GET /api/traces", r.handleListTraces)
+	mux.HandleFunc("GET /api/traces/{traceID}", r.handleGetTrace)
+	mux.HandleFunc("GET /traces/{traceID}", r.handleTracePage)
+	mux.HandleFunc("GET /{$}", r.handleIndexPage)
+
+	return mux
+}
+
+func (r *Receiver) handleTraces(w http.ResponseWriter, req *http.Request) {
+	exportReq := &coltracepb.ExportTraceServiceRequest{}
+	contentType, err := readRequest(w, req, exportReq)
+	if err != nil {
+		writeStatus(w, contentType, http.StatusBadRequest, err)
+		return
+	}
+	if contentType == contentTypeJSON {
+		if err := hexIDs(exportReq); err != nil {
+			writeStatus(w, contentType, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := r.export(req.Context(), exportReq); err != nil {
+		writeStatus(w, contentType, http.StatusInternalServerError, err)
+		return
+	}
+	writeResponse(w, contentType, &coltracepb.ExportTraceServiceResponse{})
+}
+
+// discard accepts the requests of a signal not stored
+func discard(exportReq, exportResp proto.Message) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		contentType, err := readRequest(w, req, proto.Clone(exportReq))
+		if err != nil {
+			writeStatus(w, contentType, http.StatusBadRequest, err)
+			return
+		}
+		writeResponse(w, contentType, exportResp)
+	}
+}
+
+// readRequest decodes the body, compressed or not, returning its content type
+func readRequest(w http.ResponseWriter, req *http.Request, m proto.Message) (string, error) {
+	contentType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || (contentType != contentTypeProtobuf && contentType != contentTypeJSON) {
+		return contentTypeProtobuf, fmt.Errorf("unsupported content type %q", req.Header.Get("Content-Type"))
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, req.Body, maxBodySize)
+	switch req.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return contentType, fmt.Errorf("failed to decompress body: %w", err)
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxBodySize)
+	default:
+		return contentType, fmt.Errorf("unsupported content encoding %q", req.Header.Get("Content-Encoding"))
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return contentType, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if contentType == contentTypeJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+	} else {
+		err = proto.Unmarshal(data, m)
+	}
+	if err != nil {
+		return contentType, fmt.Errorf("failed to decode body: %w", err)
+	}
+	return contentType, nil
+}
+
+// hexIDs fixes the IDs of a JSON request: OTLP/JSON has them as hex strings instead of the base64
+// of the bytes fields, so protojson decoded the hex digits as base64 and they are encoded back
+func hexIDs(req *coltracepb.ExportTraceServiceRequest) error {
+	var errs []error
+	decode := func(id *[]byte) {
+		if len(*id) == 0 {
+			return
+		}
+		b, err := hex.DecodeString(base64.StdEncoding.EncodeToString(*id))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid hex ID: %w", err))
+			return
+		}
+		*id = b
+	}
+
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				decode(&s.TraceId)
+				decode(&s.SpanId)
+				decode(&s.ParentSpanId)
+				for _, link := range s.GetLinks() {
+					decode(&link.TraceId)
+					decode(&link.SpanId)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func writeResponse(w http.ResponseWriter, contentType string, m proto.Message) {
+	var (
+		data []byte
+		err  error
+	)
+	if contentType == contentTypeJSON {
+		data, err = protojson.Marshal(m)
+	} else {
+		data, err = proto.Marshal(m)
+	}
+	if err != nil {
+		slog.Error("Failed to encode response", slog.Any("error", err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(data)
+}
+
+// writeStatus replies with the error as a google.rpc.Status, as expected by the exporters
+func writeStatus(w http.ResponseWriter, contentType string, code int, err error) {
+	slog.Warn("Rejected OTLP request", slog.Int("status_code", code), slog.Any("error", err))
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+
+	st := &spb.Status{Message: err.Error()}
+	var data []byte
+	if contentType == contentTypeJSON {
+		data, _ = protojson.Marshal(st)
+	} else {
+		data, _ = proto.Marshal(st)
+	}
+	_, _ = w.Write(data)
+}
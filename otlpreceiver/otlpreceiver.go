@@ -0,0 +1,175 @@
+// Package otlpreceiver stands in for the OpenTelemetry collector, to look at the traces of the apps
+// without Honeycomb. It receives the spans with OTLP over gRPC and HTTP (protobuf or JSON), keeps
+// them in a Store, in memory or on disk, and serves a trace waterfall UI and a JSON API by trace ID.
+// The metrics and the logs are accepted and discarded, so the apps can export them to it too.
+// It runs as the otlp-receiver app or in-process with Handler and RegisterGRPC.
+package otlpreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	// registers the gzip compressor, used by the exporters with OTEL_EXPORTER_OTLP_COMPRESSION=gzip
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+)
+
+// Time limits of the HTTP server and of the graceful shutdown
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Option changes a setting of the Receiver
+type Option func(*options)
+
+type options struct {
+	store Store
+}
+
+// WithStore keeps the spans in store, a MemoryStore with DefaultMaxTraces when not set
+func WithStore(store Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// Receiver receives the spans and serves them
+type Receiver struct {
+	store Store
+}
+
+// New creates a Receiver
+func New(opts ...Option) *Receiver {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore(DefaultMaxTraces)
+	}
+	return &Receiver{store: o.store}
+}
+
+// Store returns the store of the spans received
+func (r *Receiver) Store() Store {
+	return r.store
+}
+
+// RegisterGRPC registers the OTLP trace, metrics and logs services on the gRPC server
+func (r *Receiver) RegisterGRPC(s *grpc.Server) {
+	coltracepb.RegisterTraceServiceServer(s, &traceService{receiver: r})
+	colmetricspb.RegisterMetricsServiceServer(s, metricsService{})
+	collogspb.RegisterLogsServiceServer(s, logsService{})
+}
+
+// Run serves OTLP over gRPC on grpcAddress and, on httpAddress, OTLP over HTTP with the UI and
+// the JSON API, until the context is cancelled
+func (r *Receiver) Run(ctx context.Context, grpcAddress, httpAddress string) error {
+	grpcLis, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	httpLis, err := net.Listen("tcp", httpAddress)
+	if err != nil {
+		_ = grpcLis.Close()
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	grpcServer := grpc.NewServer()
+	r.RegisterGRPC(grpcServer)
+	httpServer := &http.Server{
+		Handler:           r.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	slog.Info("Starting OTLP receiver", slog.String("grpc", grpcLis.Addr().String()), slog.String("http", httpLis.Addr().String()))
+
+	errCh := make(chan error, 2)
+	go func() {
+		if err := grpcServer.Serve(grpcLis); err != nil {
+			errCh <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+	go func() {
+		if err := httpServer.Serve(httpLis); !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("HTTP server failed: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-errCh:
+		grpcServer.Stop()
+		return errors.Join(err, httpServer.Close())
+	}
+
+	slog.Info("Shutting down OTLP receiver")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = httpServer.Shutdown(shutdownCtx)
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+	return err
+}
+
+// export stores the spans of a request, from gRPC or HTTP
+func (r *Receiver) export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) error {
+	spans := convertSpans(req.GetResourceSpans())
+	if len(spans) == 0 {
+		return nil
+	}
+	if err := r.store.Add(spans); err != nil {
+		return fmt.Errorf("failed to store spans: %w", err)
+	}
+	slog.DebugContext(ctx, "Received spans", slog.Int("count", len(spans)))
+	return nil
+}
+
+type traceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	receiver *Receiver
+}
+
+func (s *traceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	if err := s.receiver.export(ctx, req); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+}
+
+func (metricsService) Export(context.Context, *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+type logsService struct {
+	collogspb.UnimplementedLogsServiceServer
+}
+
+func (logsService) Export(context.Context, *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
@@ -0,0 +1,149 @@
+package otlpreceiver
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Span is a span received, flattened with the name of the service from its resource
+type Span struct {
+	TraceID      string `json:"trace_id"`
+	SpanID       string `json:"span_id"`
+	ParentSpanID string `json:"parent_span_id,omitempty"`
+	Name         string `json:"name"`
+	// Kind is internal, server, client, producer or consumer
+	Kind    string `json:"kind"`
+	Service string `json:"service"`
+	// Scope is the name of the instrumentation library, i.e. of otelfiber
+	Scope      string         `json:"scope,omitempty"`
+	StartTime  time.Time      `json:"start_time"`
+	EndTime    time.Time      `json:"end_time"`
+	DurationMS float64        `json:"duration_ms"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Resource   map[string]any `json:"resource,omitempty"`
+	Events     []Event        `json:"events,omitempty"`
+	Status     Status         `json:"status"`
+}
+
+// Event is an event recorded on a span, i.e. an exception
+type Event struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Status of a span, the code is Unset, Ok or Error
+type Status struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Duration of the span
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// IsError reports if the span ended with an error status
+func (s *Span) IsError() bool {
+	return s.Status.Code == codes.Error.String()
+}
+
+// convertSpans flattens the spans of an export request
+func convertSpans(resourceSpans []*tracepb.ResourceSpans) []Span {
+	var spans []Span
+	for _, rs := range resourceSpans {
+		resource := attributesMap(rs.GetResource().GetAttributes())
+		service, _ := resource["service.name"].(string)
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				spans = append(spans, convertSpan(s, service, ss.GetScope().GetName(), resource))
+			}
+		}
+	}
+	return spans
+}
+
+func convertSpan(s *tracepb.Span, service, scope string, resource map[string]any) Span {
+	start := time.Unix(0, int64(s.GetStartTimeUnixNano())).UTC()
+	end := time.Unix(0, int64(s.GetEndTimeUnixNano())).UTC()
+
+	span := Span{
+		TraceID:    hex.EncodeToString(s.GetTraceId()),
+		SpanID:     hex.EncodeToString(s.GetSpanId()),
+		Name:       s.GetName(),
+		Kind:       trace.SpanKind(s.GetKind()).String(),
+		Service:    service,
+		Scope:      scope,
+		StartTime:  start,
+		EndTime:    end,
+		DurationMS: float64(end.Sub(start)) / float64(time.Millisecond),
+		Attributes: attributesMap(s.GetAttributes()),
+		Resource:   resource,
+		Status:     Status{Code: statusCode(s.GetStatus().GetCode()).String(), Message: s.GetStatus().GetMessage()},
+	}
+	if len(s.GetParentSpanId()) > 0 {
+		span.ParentSpanID = hex.EncodeToString(s.GetParentSpanId())
+	}
+	for _, e := range s.GetEvents() {
+		span.Events = append(span.Events, Event{
+			Name:       e.GetName(),
+			Time:       time.Unix(0, int64(e.GetTimeUnixNano())).UTC(),
+			Attributes: attributesMap(e.GetAttributes()),
+		})
+	}
+	return span
+}
+
+// statusCode maps the OTLP status codes, in a different order than the ones of the API
+func statusCode(code tracepb.Status_StatusCode) codes.Code {
+	switch code {
+	case tracepb.Status_STATUS_CODE_OK:
+		return codes.Ok
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return codes.Error
+	default:
+		return codes.Unset
+	}
+}
+
+func attributesMap(attrs []*commonpb.KeyValue) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(attrs))
+	for _, kv := range attrs {
+		m[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+	return m
+}
+
+func anyValue(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, value := range v.ArrayValue.GetValues() {
+			values[i] = anyValue(value)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		return attributesMap(v.KvlistValue.GetValues())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,393 @@
+package otlpreceiver
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Traces kept when not set with NewMemoryStore or NewDiskStore
+const DefaultMaxTraces = 1000
+
+// ErrTraceNotFound is returned by the stores for the trace IDs never received or already dropped
+var ErrTraceNotFound = errors.New("trace not found")
+
+// Store keeps the spans received, grouped by trace
+type Store interface {
+	// Add stores the spans, of one or more traces
+	Add(spans []Span) error
+	// Trace returns the spans of a trace, ordered by start time
+	Trace(traceID string) ([]Span, error)
+	// Traces returns the summaries of up to limit traces, the most recent first
+	Traces(limit int) ([]TraceSummary, error)
+}
+
+// TraceSummary describes a trace in the list of the traces received
+type TraceSummary struct {
+	TraceID string `json:"trace_id"`
+	// RootName and RootService of the root span, or of the first span when the root is missing
+	RootName    string    `json:"root_name"`
+	RootService string    `json:"root_service"`
+	StartTime   time.Time `json:"start_time"`
+	DurationMS  float64   `json:"duration_ms"`
+	SpanCount   int       `json:"span_count"`
+	ErrorCount  int       `json:"error_count"`
+	Services    []string  `json:"services"`
+}
+
+// summarize returns the summary of the spans of a trace
+func summarize(traceID string, spans []Span) TraceSummary {
+	summary := TraceSummary{TraceID: traceID, SpanCount: len(spans)}
+	if len(spans) == 0 {
+		return summary
+	}
+
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+	}
+
+	var root *Span
+	start, end := spans[0].StartTime, spans[0].EndTime
+	services := map[string]bool{}
+	for i := range spans {
+		s := &spans[i]
+		if s.StartTime.Before(start) {
+			start = s.StartTime
+		}
+		if s.EndTime.After(end) {
+			end = s.EndTime
+		}
+		if s.IsError() {
+			summary.ErrorCount++
+		}
+		services[s.Service] = true
+
+		if root == nil || rootRank(s, ids) < rootRank(root, ids) ||
+			(rootRank(s, ids) == rootRank(root, ids) && s.StartTime.Before(root.StartTime)) {
+			root = s
+		}
+	}
+
+	summary.RootName = root.Name
+	summary.RootService = root.Service
+	summary.StartTime = start
+	summary.DurationMS = float64(end.Sub(start)) / float64(time.Millisecond)
+	for service := range services {
+		summary.Services = append(summary.Services, service)
+	}
+	sort.Strings(summary.Services)
+	return summary
+}
+
+// rootRank prefers as root the spans without parent, then the ones whose parent is missing
+func rootRank(s *Span, ids map[string]bool) int {
+	switch {
+	case s.ParentSpanID == "":
+		return 0
+	case !ids[s.ParentSpanID]:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// sortSpans orders the spans by start time
+func sortSpans(spans []Span) {
+	slices.SortStableFunc(spans, func(a, b Span) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+}
+
+// groupByTrace splits the spans by trace ID, keeping the order of the traces
+func groupByTrace(spans []Span) (traceIDs []string, byTrace map[string][]Span) {
+	byTrace = map[string][]Span{}
+	for _, s := range spans {
+		if _, ok := byTrace[s.TraceID]; !ok {
+			traceIDs = append(traceIDs, s.TraceID)
+		}
+		byTrace[s.TraceID] = append(byTrace[s.TraceID], s)
+	}
+	return traceIDs, byTrace
+}
+
+// isTraceID checks that the ID has 32 hex digits, so it can be used as a file name
+func isTraceID(traceID string) bool {
+	b, err := hex.DecodeString(traceID)
+	return err == nil && len(b) == 16 && strings.ToLower(traceID) == traceID
+}
+
+// MemoryStore keeps the traces in memory, dropping the ones not updated for longest
+type MemoryStore struct {
+	mu        sync.Mutex
+	maxTraces int
+	traces    map[string]*list.Element
+	// order has the traces, the last updated at the front
+	order *list.List
+}
+
+type memoryTrace struct {
+	traceID string
+	spans   []Span
+}
+
+// NewMemoryStore keeps up to maxTraces traces, DefaultMaxTraces when not positive
+func NewMemoryStore(maxTraces int) *MemoryStore {
+	if maxTraces <= 0 {
+		maxTraces = DefaultMaxTraces
+	}
+	return &MemoryStore{
+		maxTraces: maxTraces,
+		traces:    map[string]*list.Element{},
+		order:     list.New(),
+	}
+}
+
+// Add stores the spans
+func (m *MemoryStore) Add(spans []Span) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	traceIDs, byTrace := groupByTrace(spans)
+	for _, traceID := range traceIDs {
+		if e, ok := m.traces[traceID]; ok {
+			t := e.Value.(*memoryTrace)
+			t.spans = append(t.spans, byTrace[traceID]...)
+			m.order.MoveToFront(e)
+			continue
+		}
+
+		m.traces[traceID] = m.order.PushFront(&memoryTrace{traceID: traceID, spans: byTrace[traceID]})
+		for m.order.Len() > m.maxTraces {
+			oldest := m.order.Remove(m.order.Back()).(*memoryTrace)
+			delete(m.traces, oldest.traceID)
+		}
+	}
+	return nil
+}
+
+// Trace returns a copy of the spans of a trace
+func (m *MemoryStore) Trace(traceID string) ([]Span, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.traces[traceID]
+	if !ok {
+		return nil, ErrTraceNotFound
+	}
+	spans := slices.Clone(e.Value.(*memoryTrace).spans)
+	sortSpans(spans)
+	return spans, nil
+}
+
+// Traces returns the summaries of the last limit traces updated
+func (m *MemoryStore) Traces(limit int) ([]TraceSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summaries := []TraceSummary{}
+	for e := m.order.Front(); e != nil && len(summaries) < limit; e = e.Next() {
+		t := e.Value.(*memoryTrace)
+		summaries = append(summaries, summarize(t.traceID, t.spans))
+	}
+	return summaries, nil
+}
+
+// DiskStore appends the spans of each trace, as JSON lines, to a file named after the trace ID,
+// so the traces survive the restarts of the receiver, the traces updated last are listed first
+type DiskStore struct {
+	mu        sync.Mutex
+	dir       string
+	maxTraces int
+	count     int
+}
+
+// Extension of the files of the DiskStore
+const traceFileExt = ".jsonl"
+
+// NewDiskStore keeps up to maxTraces traces in dir, DefaultMaxTraces when not positive,
+// removing the files not updated for longest
+func NewDiskStore(dir string, maxTraces int) (*DiskStore, error) {
+	if maxTraces <= 0 {
+		maxTraces = DefaultMaxTraces
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %w", err)
+	}
+
+	d := &DiskStore{dir: dir, maxTraces: maxTraces}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	d.count = len(files)
+	return d, nil
+}
+
+// Add appends the spans to the files of their traces
+func (d *DiskStore) Add(spans []Span) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	traceIDs, byTrace := groupByTrace(spans)
+	var errs []error
+	for _, traceID := range traceIDs {
+		if !isTraceID(traceID) {
+			errs = append(errs, fmt.Errorf("invalid trace ID %q", traceID))
+			continue
+		}
+		errs = append(errs, d.append(traceID, byTrace[traceID]))
+	}
+
+	if d.count > d.maxTraces {
+		errs = append(errs, d.prune())
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DiskStore) append(traceID string, spans []Span) error {
+	path := d.path(traceID)
+	_, err := os.Stat(path)
+	isNew := errors.Is(err, fs.ErrNotExist)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open trace file: %w", err)
+	}
+	if isNew {
+		d.count++
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, s := range spans {
+		if err := enc.Encode(s); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to encode span: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write trace file: %w", err)
+	}
+	return f.Close()
+}
+
+// prune removes the files over maxTraces
+func (d *DiskStore) prune() error {
+	files, err := d.files()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, f := range files[min(d.maxTraces, len(files)):] {
+		if err := os.Remove(filepath.Join(d.dir, f.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove trace file: %w", err))
+		}
+	}
+	d.count = min(d.maxTraces, len(files))
+	return errors.Join(errs...)
+}
+
+// Trace reads the spans of a trace from its file
+func (d *DiskStore) Trace(traceID string) ([]Span, error) {
+	if !isTraceID(traceID) {
+		return nil, ErrTraceNotFound
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	spans, err := d.read(traceID)
+	if err != nil {
+		return nil, err
+	}
+	sortSpans(spans)
+	return spans, nil
+}
+
+func (d *DiskStore) read(traceID string) ([]Span, error) {
+	f, err := os.Open(d.path(traceID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrTraceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	defer f.Close()
+
+	var spans []Span
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var s Span
+		if err := dec.Decode(&s); err != nil {
+			return nil, fmt.Errorf("failed to decode trace file %s: %w", f.Name(), err)
+		}
+		spans = append(spans, s)
+	}
+	return spans, nil
+}
+
+// Traces returns the summaries of the traces whose files were written last
+func (d *DiskStore) Traces(limit int) ([]TraceSummary, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []TraceSummary{}
+	for _, f := range files[:min(limit, len(files))] {
+		traceID := strings.TrimSuffix(f.Name(), traceFileExt)
+		spans, err := d.read(traceID)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summarize(traceID, spans))
+	}
+	return summaries, nil
+}
+
+// files lists the trace files, the last modified first
+func (d *DiskStore) files() ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage dir: %w", err)
+	}
+
+	var files []fs.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != traceFileExt || !isTraceID(strings.TrimSuffix(e.Name(), traceFileExt)) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// removed in the meantime
+			continue
+		}
+		files = append(files, info)
+	}
+
+	slices.SortStableFunc(files, func(a, b fs.FileInfo) int {
+		return b.ModTime().Compare(a.ModTime())
+	})
+	return files, nil
+}
+
+func (d *DiskStore) path(traceID string) string {
+	return filepath.Join(d.dir, traceID+traceFileExt)
+}
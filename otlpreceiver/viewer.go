@@ -0,0 +1,219 @@
+package otlpreceiver
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Traces listed when the limit is not set, and the most that can be requested
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// Narrowest bar of the waterfall, in percent, so that the shortest spans are visible
+const minBarWidth = 0.2
+
+// errInvalidTraceID is returned for the IDs that are not 32 hex digits
+var errInvalidTraceID = errors.New("invalid trace ID")
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"duration": formatDuration,
+}).ParseFS(templatesFS, "templates/*.html"))
+
+// Trace is the response of GET /api/traces/{traceID}
+type Trace struct {
+	TraceID string `json:"trace_id"`
+	// Spans ordered by start time
+	Spans []Span `json:"spans"`
+}
+
+// handleListTraces serves the summaries of the last traces, up to the limit query param
+func (r *Receiver) handleListTraces(w http.ResponseWriter, req *http.Request) {
+	limit, err := listLimit(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	summaries, err := r.store.Traces(limit)
+	if err != nil {
+		slog.Error("Failed to list traces", slog.Any("error", err))
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// handleGetTrace serves the spans of a trace, 404 when it was not received
+func (r *Receiver) handleGetTrace(w http.ResponseWriter, req *http.Request) {
+	traceID := strings.ToLower(req.PathValue("traceID"))
+	spans, err := r.trace(traceID)
+	if err != nil {
+		writeJSON(w, errorStatusCode(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Trace{TraceID: traceID, Spans: spans})
+}
+
+// handleIndexPage lists the last traces
+func (r *Receiver) handleIndexPage(w http.ResponseWriter, req *http.Request) {
+	limit, err := listLimit(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := r.store.Traces(limit)
+	if err != nil {
+		slog.Error("Failed to list traces", slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	render(w, "index.html", summaries)
+}
+
+// handleTracePage shows the waterfall of a trace
+func (r *Receiver) handleTracePage(w http.ResponseWriter, req *http.Request) {
+	traceID := strings.ToLower(req.PathValue("traceID"))
+	spans, err := r.trace(traceID)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatusCode(err))
+		return
+	}
+	render(w, "trace.html", newWaterfall(traceID, spans))
+}
+
+func (r *Receiver) trace(traceID string) ([]Span, error) {
+	if !isTraceID(traceID) {
+		return nil, fmt.Errorf("%w %q, it must have 32 hex digits", errInvalidTraceID, traceID)
+	}
+	spans, err := r.store.Trace(traceID)
+	if err != nil && !errors.Is(err, ErrTraceNotFound) {
+		slog.Error("Failed to read trace", slog.String("trace_id", traceID), slog.Any("error", err))
+	}
+	return spans, err
+}
+
+func errorStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrTraceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidTraceID):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func listLimit(req *http.Request) (int, error) {
+	v := req.URL.Query().Get("limit")
+	if v == "" {
+		return defaultListLimit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > maxListLimit {
+		return 0, fmt.Errorf("limit %q must be a number between 1 and %d", v, maxListLimit)
+	}
+	return limit, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write response", slog.Any("error", err))
+	}
+}
+
+func render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		slog.Error("Failed to render page", slog.String("page", name), slog.Any("error", err))
+	}
+}
+
+// waterfall is a trace with its spans in tree order, each placed on the timeline of the trace
+type waterfall struct {
+	TraceSummary
+	Rows []waterfallRow
+}
+
+type waterfallRow struct {
+	Span
+	Depth int
+	// Offset and Width of the bar, in percent of the duration of the trace
+	Offset float64
+	Width  float64
+	// Hue of the color of the service
+	Hue uint32
+}
+
+func newWaterfall(traceID string, spans []Span) waterfall {
+	summary := summarize(traceID, spans)
+	total := time.Duration(summary.DurationMS * float64(time.Millisecond))
+
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+	}
+
+	// the spans are ordered by start time, so are the children of each span
+	var roots []Span
+	children := map[string][]Span{}
+	for _, s := range spans {
+		if s.ParentSpanID == "" || !ids[s.ParentSpanID] {
+			roots = append(roots, s)
+			continue
+		}
+		children[s.ParentSpanID] = append(children[s.ParentSpanID], s)
+	}
+
+	w := waterfall{TraceSummary: summary}
+	visited := map[string]bool{}
+	var walk func(s Span, depth int)
+	walk = func(s Span, depth int) {
+		if visited[s.SpanID] {
+			return
+		}
+		visited[s.SpanID] = true
+
+		row := waterfallRow{Span: s, Depth: depth, Width: 100, Hue: serviceHue(s.Service)}
+		if total > 0 {
+			row.Offset = float64(s.StartTime.Sub(summary.StartTime)) / float64(total) * 100
+			row.Width = max(float64(s.Duration())/float64(total)*100, minBarWidth)
+		}
+		w.Rows = append(w.Rows, row)
+
+		for _, child := range children[s.SpanID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	return w
+}
+
+// serviceHue gives each service its own color
+func serviceHue(service string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(service))
+	return h.Sum32() % 360
+}
+
+func formatDuration(ms float64) string {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond).String()
+}
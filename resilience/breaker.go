@@ -0,0 +1,171 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen is returned, wrapped, for the calls rejected by an open breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State of a circuit breaker
+type State int
+
+const (
+	// StateClosed lets all the calls through
+	StateClosed State = iota
+	// StateOpen rejects all the calls
+	StateOpen
+	// StateHalfOpen lets a single call through to check if the upstream recovered
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// CircuitBreaker counts the consecutive failures of an upstream
+type CircuitBreaker struct {
+	upstream         string
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a closed breaker for the upstream
+func NewCircuitBreaker(upstream string, failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		upstream:         upstream,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow returns ErrCircuitOpen if the call must not be made,
+// otherwise the outcome of the call must be passed to Record
+func (b *CircuitBreaker) Allow(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTimeout {
+		b.setState(ctx, StateHalfOpen)
+	}
+
+	switch {
+	case b.state == StateOpen, b.state == StateHalfOpen && b.probing:
+		trace.SpanFromContext(ctx).AddEvent(rejectedEvent, trace.WithAttributes(upstreamKey.String(b.upstream)))
+		return fmt.Errorf("%s: %w", b.upstream, ErrCircuitOpen)
+	case b.state == StateHalfOpen:
+		b.probing = true
+	}
+	return nil
+}
+
+// Record updates the breaker with the outcome of a call allowed by Allow
+func (b *CircuitBreaker) Record(ctx context.Context, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		if b.state != StateClosed {
+			b.setState(ctx, StateClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.failureThreshold {
+		b.setState(ctx, StateOpen)
+	}
+}
+
+// release lets another call through a half-open breaker when the call allowed
+// ended without an outcome, i.e. it was cancelled by the caller
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// setState must be called holding the lock
+func (b *CircuitBreaker) setState(ctx context.Context, state State) {
+	from := b.state
+	b.state = state
+	b.probing = false
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+
+	trace.SpanFromContext(ctx).AddEvent(stateChangeEvent, trace.WithAttributes(
+		upstreamKey.String(b.upstream),
+		fromKey.String(from.String()),
+		toKey.String(state.String()),
+	))
+	slog.WarnContext(ctx, "Circuit breaker state changed",
+		slog.String("upstream", b.upstream),
+		slog.String("from", from.String()),
+		slog.String("to", state.String()),
+	)
+}
+
+// breakers holds a CircuitBreaker per upstream
+type breakers struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func newBreakers(o *options) *breakers {
+	return &breakers{
+		failureThreshold: o.failureThreshold,
+		openTimeout:      o.openTimeout,
+		breakers:         make(map[string]*CircuitBreaker),
+	}
+}
+
+// get returns the breaker of the upstream, nil when the breakers are disabled
+func (b *breakers) get(upstream string) *CircuitBreaker {
+	if b.failureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[upstream]
+	if !ok {
+		breaker = NewCircuitBreaker(upstream, b.failureThreshold, b.openTimeout)
+		b.breakers[upstream] = breaker
+	}
+	return breaker
+}
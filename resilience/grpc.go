@@ -0,0 +1,68 @@
+package resilience
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Codes retried, the server is expected to recover
+var retryableCodes = []codes.Code{
+	codes.Unavailable,
+	codes.ResourceExhausted,
+}
+
+// Codes counted as failures of the server, the others (i.e. InvalidArgument) are errors of the caller
+var failureCodes = []codes.Code{
+	codes.Unavailable,
+	codes.DeadlineExceeded,
+	codes.ResourceExhausted,
+	codes.Internal,
+	codes.Unknown,
+}
+
+// UnaryClientInterceptor retries the unary RPCs failing with Unavailable or ResourceExhausted,
+// with a circuit breaker per target. Used with the otelgrpc stats handler each attempt has its own span.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts...)
+	breakers := newBreakers(o)
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		upstream := cc.Target()
+		breaker := breakers.get(upstream)
+
+		for attempt := 1; ; attempt++ {
+			if breaker != nil {
+				if err := breaker.Allow(ctx); err != nil {
+					return status.Error(codes.Unavailable, err.Error())
+				}
+			}
+
+			err := invoker(ctx, method, req, reply, cc, callOpts...)
+			code := status.Code(err)
+
+			if breaker != nil {
+				if ctx.Err() != nil {
+					breaker.release()
+				} else {
+					breaker.Record(ctx, !slices.Contains(failureCodes, code))
+				}
+			}
+
+			if !slices.Contains(retryableCodes, code) || attempt >= o.maxAttempts || ctx.Err() != nil {
+				recordAttempt(ctx, upstream, attempt, code.String(), err, 0)
+				return err
+			}
+
+			backoff := o.backoff(attempt)
+			recordAttempt(ctx, upstream, attempt, code.String(), err, backoff)
+
+			if err := wait(ctx, backoff); err != nil {
+				return status.FromContextError(err).Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+)
+
+// Status codes retried, the upstream is expected to recover
+var retryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Methods retried, the others might not be safe to repeat
+var idempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// NewTransport wraps a RoundTripper, i.e. the otelhttp one so that each attempt has its own span,
+// retrying the idempotent requests and with a circuit breaker per host.
+// The response of the last attempt is returned, an error only if there is no response.
+func NewTransport(next http.RoundTripper, opts ...Option) http.RoundTripper {
+	o := newOptions(opts...)
+	return &transport{
+		next:     next,
+		options:  o,
+		breakers: newBreakers(o),
+	}
+}
+
+type transport struct {
+	next http.RoundTripper
+	*options
+	breakers *breakers
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	upstream := req.URL.Host
+	breaker := t.breakers.get(upstream)
+
+	maxAttempts := t.maxAttempts
+	if !canRetry(req) {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if breaker != nil {
+			if err := breaker.Allow(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			if breaker != nil {
+				breaker.release()
+			}
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+
+		// 429 is retried without counting as a failure of the upstream
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		retryable := err != nil || slices.Contains(retryableStatusCodes, resp.StatusCode)
+
+		if breaker != nil {
+			if ctx.Err() != nil {
+				breaker.release()
+			} else {
+				breaker.Record(ctx, !failed)
+			}
+		}
+
+		outcome := outcomeOf(resp, err)
+		if !retryable || attempt >= maxAttempts || ctx.Err() != nil {
+			recordAttempt(ctx, upstream, attempt, outcome, err, 0)
+			return resp, err
+		}
+
+		backoff := t.backoff(attempt)
+		recordAttempt(ctx, upstream, attempt, outcome, err, backoff)
+
+		// the body of the discarded response is drained to reuse the connection
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if err := wait(ctx, backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// canRetry checks that the request is idempotent and its body can be sent again
+func canRetry(req *http.Request) bool {
+	if !slices.Contains(idempotentMethods, req.Method) {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns the request for the attempt, with a new copy of the body after the first one
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind the request body: %w", err)
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func outcomeOf(resp *http.Response, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case err != nil:
+		return "error"
+	default:
+		return resp.Status
+	}
+}
@@ -0,0 +1,134 @@
+// Package resilience retries the outbound HTTP and gRPC calls with exponential backoff and jitter
+// and stops calling an upstream that keeps failing with a circuit breaker.
+//
+// Each attempt and each change of state of a breaker is recorded as an event of the span in the
+// context of the call, i.e. the span of the Fiber handler, while the instrumentation wrapped by
+// the retries creates a client span per attempt.
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults used when the options are not set
+const (
+	defaultMaxAttempts      = 3
+	defaultInitialBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// Names of the span events and attributes
+const (
+	attemptEvent     = "resilience.attempt"
+	stateChangeEvent = "circuit_breaker.state_change"
+	rejectedEvent    = "circuit_breaker.rejected"
+
+	upstreamKey = attribute.Key("resilience.upstream")
+	attemptKey  = attribute.Key("resilience.attempt")
+	outcomeKey  = attribute.Key("resilience.outcome")
+	backoffKey  = attribute.Key("resilience.backoff")
+	fromKey     = attribute.Key("circuit_breaker.from")
+	toKey       = attribute.Key("circuit_breaker.to")
+)
+
+// Option changes the retries and the circuit breakers of NewTransport and UnaryClientInterceptor
+type Option func(*options)
+
+type options struct {
+	maxAttempts      int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	openTimeout      time.Duration
+}
+
+// WithMaxAttempts sets how many times a call is tried, 1 disables the retries
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled at each attempt up to max
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.initialBackoff = initial
+		o.maxBackoff = max
+	}
+}
+
+// WithCircuitBreaker opens the breaker of an upstream after failureThreshold consecutive failures,
+// after openTimeout a single call is let through to check if the upstream recovered.
+// A threshold of 0 disables the breakers.
+func WithCircuitBreaker(failureThreshold int, openTimeout time.Duration) Option {
+	return func(o *options) {
+		o.failureThreshold = failureThreshold
+		o.openTimeout = openTimeout
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		maxAttempts:      defaultMaxAttempts,
+		initialBackoff:   defaultInitialBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		failureThreshold: defaultFailureThreshold,
+		openTimeout:      defaultOpenTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.maxAttempts < 1 {
+		o.maxAttempts = 1
+	}
+	return o
+}
+
+// backoff returns the wait before the retry following the attempt, with full jitter
+// to avoid all the clients retrying at the same time
+func (o *options) backoff(attempt int) time.Duration {
+	d := o.initialBackoff << (attempt - 1)
+	if d <= 0 || d > o.maxBackoff {
+		d = o.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// wait sleeps for the backoff, returning early if the context is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// recordAttempt adds an event for the attempt to the span in the context
+func recordAttempt(ctx context.Context, upstream string, attempt int, outcome string, err error, backoff time.Duration) {
+	attrs := []attribute.KeyValue{
+		upstreamKey.String(upstream),
+		attemptKey.Int(attempt),
+		outcomeKey.String(outcome),
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	if backoff > 0 {
+		attrs = append(attrs, backoffKey.String(backoff.String()))
+	}
+	trace.SpanFromContext(ctx).AddEvent(attemptEvent, trace.WithAttributes(attrs...))
+}
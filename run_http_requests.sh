@@ -11,5 +11,11 @@ sleep 2
 curl http://localhost:8080/hello-resty
 sleep 2
 curl http://localhost:8080/hello-grpc
+sleep 2
+curl -N http://localhost:8080/hello-grpc-server-stream
+sleep 2
+curl http://localhost:8080/hello-grpc-client-stream
+sleep 2
+curl http://localhost:8080/hello-grpc-bidi-stream
 
 
@@ -4,57 +4,52 @@ import (
 	"context"
 	"io"
 	"log"
+	"os"
 	"time"
 
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-const externalURL = "https://pokeapi.co/api/v2/pokemon/ditto"
-
 var tracer trace.Tracer
 
 func init() {
 	tracer = otel.Tracer("github.com/emanuelef/go-fiber-honeycomb/sample")
 }
 
-func InitializeGlobalTracerProvider() (*sdktrace.TracerProvider, error) {
-	// OpenTelemetry exporter for tracing telemetry to be written to an output destination as JSON.
-	exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// EXTERNAL_URL can point to the fake-upstream app to run offline
+	cfg, err := config.Load()
 	if err != nil {
-		return nil, err
+		log.Fatalln("Unable to load the config", err)
 	}
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exp),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
+
+	// The sample prints the spans to stdout unless another exporter is selected
+	exporterName, ok := os.LookupEnv("OTEL_TRACES_EXPORTER")
+	if !ok {
+		exporterName = otel_instrumentation.ExporterConsole
+	}
+
+	exp, err := otel_instrumentation.NewSpanExporter(ctx, exporterName, os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	if err != nil {
+		log.Fatalln("Unable to create the exporter", err)
+	}
+
+	tp, _, err := otel_instrumentation.InitializeGlobalTracerProvider(ctx,
+		otel_instrumentation.WithExporter(exp),
+		otel_instrumentation.WithResourceAttributes(
 			semconv.ServiceName("example"),
 			semconv.ServiceVersion("0.0.1"),
-		)),
-	)
-	otel.SetTracerProvider(tp)
-
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
 		),
 	)
-
-	return tp, nil
-}
-
-func main() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	tp, err := InitializeGlobalTracerProvider()
 	if err != nil {
 		log.Fatalln("Unable to create a global trace provider", err)
 	}
@@ -65,8 +60,13 @@ func main() {
 
 	ctx, childSpan := tracer.Start(ctx, "custom-span")
 	time.Sleep(1 * time.Second)
-	respClient, _ := otelhttp.Get(ctx, externalURL)
-	_, _ = io.ReadAll(respClient.Body)
+	respClient, err := otelhttp.Get(ctx, cfg.ExternalURL)
+	if err != nil {
+		log.Println("Request failed", err)
+	} else {
+		_, _ = io.ReadAll(respClient.Body)
+		_ = respClient.Body.Close()
+	}
 	childSpan.End()
 	time.Sleep(1 * time.Second)
 }
@@ -2,75 +2,83 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"log"
-	"os"
+	"net/http"
 	"time"
 
-	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/healthcheck"
+	"github.com/emanuelef/go-fiber-honeycomb/httpclient"
+	"github.com/emanuelef/go-fiber-honeycomb/resilience"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/compress"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/recover"
 
-	"github.com/gofiber/contrib/otelfiber"
-
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
-const externalURL = "https://pokeapi.co/api/v2/pokemon/ditto"
-
 var tracer trace.Tracer
 
 func init() {
 	tracer = otel.Tracer("github.com/emanuelef/go-fiber-honeycomb/secondary")
 }
 
-func getEnv(key, fallback string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		value = fallback
-	}
-	return value
-}
-
 func main() {
-	ctx := context.Background()
-	tp, exp, err := otel_instrumentation.InitializeGlobalTracerProvider(ctx)
-
-	// Handle shutdown to ensure all sub processes are closed correctly and telemetry is exported
-	defer func() {
-		_ = exp.Shutdown(ctx)
-		_ = tp.Shutdown(ctx)
-	}()
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
 
+	cfg, err := config.Load(config.WithDefaultPort(8082))
 	if err != nil {
-		log.Fatalf("failed to initialize OpenTelemetry: %e", err)
+		log.Fatal(err)
 	}
 
-	app := fiber.New()
+	externalURL := cfg.ExternalURL
+
+	// Shared by the handlers, the failed requests to the external URL are retried
+	httpClient := &http.Client{
+		Transport: resilience.NewTransport(
+			httpclient.NewTransport(
+				httpclient.WithTimeout(cfg.HTTPClient.Timeout),
+				httpclient.WithHostTimeouts(cfg.HTTPClient.HostTimeouts),
+				httpclient.WithoutClientTrace(),
+			),
+			resilience.WithMaxAttempts(cfg.Resilience.MaxAttempts),
+			resilience.WithBackoff(cfg.Resilience.InitialBackoff, cfg.Resilience.MaxBackoff),
+			resilience.WithCircuitBreaker(cfg.Resilience.FailureThreshold, cfg.Resilience.OpenTimeout),
+		),
+	}
 
-	app.Use(otelfiber.Middleware())
+	app, err := server.NewFiberApp(ctx,
+		server.WithAddress(cfg.Address()),
+		server.WithUntracedPaths("/health", "/debug/config"),
+		server.WithReadinessChecks(healthcheck.New(
+			healthcheck.WithNonCriticalCheck("otlp", healthcheck.OTLPCollector()),
+		)),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Checked by the main app, not traced
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.Send(nil)
+	})
 
-	app.Use(recover.New())
-	app.Use(cors.New())
-	app.Use(compress.New())
+	// Effective config, with the secrets redacted
+	app.Get("/debug/config", func(c *fiber.Ctx) error {
+		return c.JSON(cfg.Redacted())
+	})
 
 	app.Get("/hello", func(c *fiber.Ctx) error {
-		_, err := otelhttp.Get(c.UserContext(), externalURL)
-		if err != nil {
+		if _, err := get(c.UserContext(), httpClient, externalURL); err != nil {
 			return fiber.ErrInternalServerError
 		}
 
-		resp, err := otelhttp.Get(c.UserContext(), externalURL)
-		_, _ = io.ReadAll(resp.Body)
-
+		status, err := get(c.UserContext(), httpClient, externalURL)
 		if err != nil {
 			return fiber.ErrInternalServerError
 		}
@@ -82,20 +90,40 @@ func main() {
 		// Create a child span
 		ctx, childSpan := tracer.Start(c.UserContext(), "custom-span-secondary")
 		time.Sleep(10 * time.Millisecond)
-		resp, _ = otelhttp.Get(ctx, externalURL)
-		_, _ = io.ReadAll(resp.Body)
+		_, err = get(ctx, httpClient, externalURL)
+		if err != nil {
+			childSpan.RecordError(err)
+			childSpan.SetStatus(codes.Error, err.Error())
+		}
 		childSpan.End()
+		if err != nil {
+			return fiber.ErrInternalServerError
+		}
 		time.Sleep(20 * time.Millisecond)
 
-		return c.SendString(resp.Status)
+		return c.SendString(status)
 	})
 
-	host := getEnv("HOST", "localhost")
-	port := getEnv("PORT", "8082")
-	hostAddress := fmt.Sprintf("%s:%s", host, port)
+	if err := app.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// get calls the URL and reads the whole body, returning the status of the response
+func get(ctx context.Context, client *http.Client, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
 
-	err = app.Listen(hostAddress)
+	resp, err := client.Do(req)
 	if err != nil {
-		log.Panic(err)
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		return "", err
 	}
+	return resp.Status, nil
 }
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/healthcheck"
+	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+	"github.com/gofiber/contrib/otelfiber"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// Probe endpoints served by all the apps, never traced
+const (
+	livenessPath  = "/livez"
+	readinessPath = "/readyz"
+)
+
+// FiberApp is a Fiber app with OpenTelemetry, recover, cors and compress middlewares
+// and the /livez and /readyz probes
+type FiberApp struct {
+	*fiber.App
+	*lifecycle
+	// Address the app listens on, set with WithAddress
+	Address string
+}
+
+// NewFiberApp sets up the telemetry and creates the app, the routes can then be added
+// and the app started with Run
+func NewFiberApp(ctx context.Context, opts ...Option) (*FiberApp, error) {
+	o := newOptions(opts...)
+
+	shutdown, err := o.setupTelemetry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := o.fiberConfig
+	// tcp4 by default in Fiber, tcp listens on the IPv6 addresses too, i.e. HOST=::1
+	if cfg.Network == "" {
+		cfg.Network = fiber.NetworkTCP
+	}
+	app := fiber.New(cfg)
+
+	untracedPaths := append([]string{livenessPath, readinessPath}, o.untracedPaths...)
+
+	// Besides the spans otelfiber records the http.server.* metrics
+	// (duration, active requests, request and response size) with the global MeterProvider
+	app.Use(otelfiber.Middleware(otelfiber.WithNext(func(c *fiber.Ctx) bool {
+		return slices.Contains(untracedPaths, c.Path()) || otel_instrumentation.NeverSampled(c.Path())
+	})))
+
+	app.Use(recover.New())
+	app.Use(cors.New())
+	// the Server-Sent Events must be sent as they are written, not buffered by the compression
+	app.Use(compress.New(compress.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+		},
+	}))
+
+	// The process is up and serving, the dependencies are not checked
+	// so that an upstream failure doesn't restart the app
+	app.Get(livenessPath, func(c *fiber.Ctx) error {
+		return c.JSON(healthcheck.Report{Status: healthcheck.StatusOK, Checks: map[string]healthcheck.CheckResult{}})
+	})
+
+	readiness := o.readiness
+	if readiness == nil {
+		readiness = healthcheck.New()
+	}
+
+	// Ready to receive traffic when all the dependencies are healthy
+	app.Get(readinessPath, func(c *fiber.Ctx) error {
+		report := readiness.Run(c.UserContext())
+		if !report.Healthy() {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+		return c.JSON(report)
+	})
+
+	return &FiberApp{
+		App:       app,
+		lifecycle: newLifecycle(o, shutdown),
+		Address:   o.address,
+	}, nil
+}
+
+// Run serves the requests until the context is cancelled, then it waits for the in-flight
+// requests and the background tasks to complete and flushes the telemetry
+func (a *FiberApp) Run(ctx context.Context) error {
+	return a.run(ctx,
+		func() error {
+			return a.App.Listen(a.Address)
+		},
+		func(timeout time.Duration) error {
+			return a.App.ShutdownWithTimeout(timeout)
+		},
+	)
+}
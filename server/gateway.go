@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Time limit to read the headers of the requests to the gateway
+const gatewayReadHeaderTimeout = 10 * time.Second
+
+// GatewayRegisterFunc registers the HTTP handlers of a service on the mux, i.e. the generated
+// protos.RegisterGreeterHandler
+type GatewayRegisterFunc func(ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn) error
+
+// WithGateway serves on address the REST/JSON gateway of the services, transcoding the requests
+// with the google.api.http rules of the proto files and calling the gRPC server
+func WithGateway(address string, register ...GatewayRegisterFunc) Option {
+	return func(o *options) {
+		o.gatewayAddress = address
+		o.gatewayRegister = append(o.gatewayRegister, register...)
+	}
+}
+
+// gateway calls the gRPC server over a channel instrumented with otelgrpc, so the span of
+// the HTTP request, continuing the trace in the traceparent header, is the parent of the RPC
+type gateway struct {
+	address string
+	conn    *grpc.ClientConn
+	server  *http.Server
+}
+
+func newGateway(ctx context.Context, o *options, grpcAddress string) (*gateway, error) {
+	conn, err := grpc.NewClient(grpcAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gateway client for %s: %w", grpcAddress, err)
+	}
+
+	mux := runtime.NewServeMux(runtime.WithMiddlewares(routeMiddleware))
+	for _, register := range o.gatewayRegister {
+		if err := register(ctx, mux, conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to register gateway handlers: %w", err)
+		}
+	}
+
+	return &gateway{
+		address: o.gatewayAddress,
+		conn:    conn,
+		server: &http.Server{
+			Addr:              o.gatewayAddress,
+			Handler:           otelhttp.NewHandler(mux, "grpc-gateway"),
+			ReadHeaderTimeout: gatewayReadHeaderTimeout,
+		},
+	}, nil
+}
+
+// routeMiddleware names the span of the request after the matched HTTP rule, i.e. GET /v1/greeter/hello/{greeting}
+func routeMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		if pattern, ok := runtime.HTTPPattern(r.Context()); ok {
+			// the pattern prints the variables as {greeting=*}, as in the proto files they are {greeting}
+			route := strings.ReplaceAll(pattern.String(), "=*}", "}")
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		next(w, r, pathParams)
+	}
+}
+
+func (g *gateway) listen() (net.Listener, error) {
+	lis, err := net.Listen("tcp", g.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	return lis, nil
+}
+
+func (g *gateway) serve(lis net.Listener) error {
+	if err := g.server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("gateway failed: %w", err)
+	}
+	return nil
+}
+
+// shutdown waits for the in-flight requests, so that their RPCs complete before the gRPC server stops
+func (g *gateway) shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := g.server.Shutdown(ctx)
+	return errors.Join(err, g.conn.Close())
+}
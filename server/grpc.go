@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/grpcmiddleware"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+// Shortest interval between the keepalive pings of the clients
+const keepaliveMinTime = 10 * time.Second
+
+// GRPCServer is a gRPC server with the OpenTelemetry stats handler, the interceptors of grpcmiddleware,
+// the health service and reflection registered
+type GRPCServer struct {
+	*grpc.Server
+	*lifecycle
+	// Address the server listens on, set with WithAddress
+	Address string
+	// Health is the grpc.health.v1 service, all the registered services are SERVING once
+	// Run is called and NOT_SERVING when the server shuts down. The status of a service
+	// can be changed with Health.SetServingStatus, i.e. when one of its dependencies fails.
+	Health *health.Server
+
+	gateway *gateway
+}
+
+// NewGRPCServer sets up the telemetry and creates the server, the services can then be
+// registered and the server started with Run
+func NewGRPCServer(ctx context.Context, opts ...Option) (*GRPCServer, error) {
+	o := newOptions(opts...)
+
+	shutdown, err := o.setupTelemetry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serverOptions := append([]grpc.ServerOption{
+		// the health checks are frequent and the Watch streams of the clients last as long as the connection
+		grpc.StatsHandler(otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))),
+		// logging, panic recovery, max deadline and validation, run with the span of the RPC in the context
+		grpc.ChainUnaryInterceptor(grpcmiddleware.UnaryServerInterceptors(o.interceptors...)...),
+		grpc.ChainStreamInterceptor(grpcmiddleware.StreamServerInterceptors(o.interceptors...)...),
+		// the clients ping the idle connections, the default policy closes them after pings more frequent than 5 minutes
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             keepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+	}, o.grpcOptions...)
+	grpcServer := grpc.NewServer(serverOptions...)
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	// Register reflection service on gRPC server.
+	reflection.Register(grpcServer)
+
+	s := &GRPCServer{
+		Server:    grpcServer,
+		lifecycle: newLifecycle(o, shutdown),
+		Address:   o.address,
+		Health:    healthServer,
+	}
+
+	if o.gatewayAddress != "" {
+		s.gateway, err = newGateway(ctx, o, s.Address)
+		if err != nil {
+			_ = shutdown(ctx)
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Run serves the requests until the context is cancelled, then it waits for the in-flight
+// RPCs and the background tasks to complete and flushes the telemetry
+func (s *GRPCServer) Run(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		_ = s.shutdownTelemetry(ctx)
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	var gatewayLis net.Listener
+	if s.gateway != nil {
+		gatewayLis, err = s.gateway.listen()
+		if err != nil {
+			_ = lis.Close()
+			_ = s.shutdownTelemetry(ctx)
+			return err
+		}
+	}
+
+	for service := range s.GetServiceInfo() {
+		s.Health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	slog.Info("Starting server", slog.String("address", lis.Addr().String()))
+	if s.gateway != nil {
+		slog.Info("Starting gateway", slog.String("address", gatewayLis.Addr().String()))
+	}
+
+	return s.run(ctx, func() error { return s.serve(lis, gatewayLis) }, s.gracefulStop)
+}
+
+// serve serves the gRPC server and the gateway, if any, until both are stopped
+func (s *GRPCServer) serve(lis, gatewayLis net.Listener) error {
+	if s.gateway == nil {
+		return s.Server.Serve(lis)
+	}
+
+	gatewayErr := make(chan error, 1)
+	go func() {
+		gatewayErr <- s.gateway.serve(gatewayLis)
+	}()
+
+	err := s.Server.Serve(lis)
+	// the gateway is already shut down when the server is stopped gracefully
+	_ = s.gateway.server.Close()
+	return errors.Join(err, <-gatewayErr)
+}
+
+// gracefulStop reports NOT_SERVING, so that the clients checking the health stop sending new RPCs,
+// and waits for the pending ones, forcing the stop after the timeout. The requests to the gateway
+// are drained first, while their RPCs can still be served.
+func (s *GRPCServer) gracefulStop(timeout time.Duration) error {
+	s.Health.Shutdown()
+
+	var gatewayErr error
+	if s.gateway != nil {
+		start := time.Now()
+		if err := s.gateway.shutdown(timeout); err != nil {
+			gatewayErr = fmt.Errorf("failed to stop gateway: %w", err)
+		}
+		timeout -= time.Since(start)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return gatewayErr
+	case <-time.After(timeout):
+		s.Server.Stop()
+		return errors.Join(gatewayErr, fmt.Errorf("pending RPCs still running after %s", timeout))
+	}
+}
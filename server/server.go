@@ -0,0 +1,200 @@
+// Package server creates the Fiber apps and gRPC servers of the project with the
+// telemetry, the middlewares and the listen address set up the same way
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/grpcmiddleware"
+	"github.com/emanuelef/go-fiber-honeycomb/healthcheck"
+	"github.com/emanuelef/go-fiber-honeycomb/otel_instrumentation"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/grpc"
+)
+
+// Option changes a setting used by NewFiberApp and NewGRPCServer
+type Option func(*options)
+
+// Time allowed to the in-flight requests to complete when shutting down
+const defaultShutdownTimeout = 10 * time.Second
+
+type options struct {
+	address         string
+	shutdownTimeout time.Duration
+	untracedPaths   []string
+	telemetry       []otel_instrumentation.Option
+	skipTelemetry   bool
+	fiberConfig     fiber.Config
+	grpcOptions     []grpc.ServerOption
+	interceptors    []grpcmiddleware.Option
+	gatewayAddress  string
+	gatewayRegister []GatewayRegisterFunc
+	readiness       *healthcheck.Checker
+}
+
+// WithAddress sets the address to listen on, i.e. from config.Config.Address
+func WithAddress(address string) Option {
+	return func(o *options) {
+		o.address = address
+	}
+}
+
+// WithShutdownTimeout sets the time allowed to the in-flight requests to complete on shutdown
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithReadinessChecks sets the checks of the dependencies reported by /readyz
+func WithReadinessChecks(checker *healthcheck.Checker) Option {
+	return func(o *options) {
+		o.readiness = checker
+	}
+}
+
+// WithUntracedPaths excludes the paths from tracing, i.e. frequent health checks
+func WithUntracedPaths(paths ...string) Option {
+	return func(o *options) {
+		o.untracedPaths = append(o.untracedPaths, paths...)
+	}
+}
+
+// WithTelemetryOptions passes the options to otel_instrumentation.Setup
+func WithTelemetryOptions(opts ...otel_instrumentation.Option) Option {
+	return func(o *options) {
+		o.telemetry = append(o.telemetry, opts...)
+	}
+}
+
+// WithoutTelemetrySetup uses the global providers already set, i.e. by telemetrytest,
+// instead of setting them up with otel_instrumentation.Setup, they are not shut down by Run
+func WithoutTelemetrySetup() Option {
+	return func(o *options) {
+		o.skipTelemetry = true
+	}
+}
+
+// WithFiberConfig replaces the default Fiber config
+func WithFiberConfig(cfg fiber.Config) Option {
+	return func(o *options) {
+		o.fiberConfig = cfg
+	}
+}
+
+// WithInterceptorOptions passes the options to the interceptors of the gRPC server,
+// i.e. grpcmiddleware.WithMaxDeadline
+func WithInterceptorOptions(opts ...grpcmiddleware.Option) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, opts...)
+	}
+}
+
+// WithGRPCServerOptions adds options to the gRPC server, after the OpenTelemetry stats handler
+func WithGRPCServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.grpcOptions = append(o.grpcOptions, opts...)
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{shutdownTimeout: defaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// setupTelemetry initialises OpenTelemetry, failing if it can't be set up
+func (o *options) setupTelemetry(ctx context.Context) (func(context.Context) error, error) {
+	if o.skipTelemetry {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	shutdown, err := otel_instrumentation.Setup(ctx, o.telemetry...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenTelemetry: %w", err)
+	}
+	return shutdown, nil
+}
+
+// SignalContext returns a context cancelled on SIGINT or SIGTERM, i.e. when docker compose
+// stops the containers, to be passed to Run
+func SignalContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
+
+// lifecycle runs the background tasks of a server and, on shutdown,
+// waits for them before flushing the telemetry
+type lifecycle struct {
+	shutdownTimeout   time.Duration
+	shutdownTelemetry func(context.Context) error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newLifecycle(o *options, shutdownTelemetry func(context.Context) error) *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{
+		shutdownTimeout:   o.shutdownTimeout,
+		shutdownTelemetry: shutdownTelemetry,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+}
+
+// Go runs a background task, i.e. a ticker, its context is cancelled
+// after the in-flight requests completed and the server waits for it to return
+func (l *lifecycle) Go(task func(ctx context.Context)) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		task(l.ctx)
+	}()
+}
+
+// run serves until the context is cancelled or serve fails, then it stops the server,
+// the background tasks and finally flushes the telemetry
+func (l *lifecycle) run(ctx context.Context, serve func() error, stop func(timeout time.Duration) error) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	var errs []error
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			errs = append(errs, err)
+		}
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for the in-flight requests", slog.Duration("timeout", l.shutdownTimeout))
+		if err := stop(l.shutdownTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop server: %w", err))
+		}
+		if err := <-serveErr; err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	l.cancel()
+	l.wg.Wait()
+
+	// the telemetry is flushed even if the context passed to run is already cancelled
+	if err := l.shutdownTelemetry(context.WithoutCancel(ctx)); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -14,3 +14,4 @@ echo
 sed "s/your_key_here/$token/" .env.example >.env
 sed "s/your_key_here/$token/" ./secondary/.env.example >./secondary/.env
 sed "s/your_key_here/$token/" ./grpc-server/.env.example >./grpc-server/.env
+sed "s/your_key_here/$token/" ./fake-upstream/.env.example >./fake-upstream/.env
@@ -0,0 +1,181 @@
+package telemetrytest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Time waited for a span to end, the server spans of gRPC can end after the client got the response
+const waitTimeout = 2 * time.Second
+
+// RequireSpan waits for the span with the name to end and returns it, failing the test
+// if there is none or more than one
+func (r *Recorder) RequireSpan(t testing.TB, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	return r.requireOne(t, fmt.Sprintf("span named %q", name), func(s sdktrace.ReadOnlySpan) bool {
+		return s.Name() == name
+	})
+}
+
+// RequireServerSpan is like RequireSpan for the server spans, i.e. of the route of a Fiber app or
+// of a gRPC method, which has the same name of the client span when the client is instrumented too
+func (r *Recorder) RequireServerSpan(t testing.TB, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	return r.requireOne(t, fmt.Sprintf("server span named %q", name), func(s sdktrace.ReadOnlySpan) bool {
+		return s.Name() == name && s.SpanKind() == trace.SpanKindServer
+	})
+}
+
+// RequireChild waits for the child of parent with the name to end and returns it,
+// failing the test if there is none or more than one
+func (r *Recorder) RequireChild(t testing.TB, parent sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	return r.requireOne(t, fmt.Sprintf("span named %q under %q", name, parent.Name()), func(s sdktrace.ReadOnlySpan) bool {
+		return s.Name() == name &&
+			s.Parent().SpanID() == parent.SpanContext().SpanID() &&
+			s.Parent().TraceID() == parent.SpanContext().TraceID()
+	})
+}
+
+// RequireNoSpan fails the test if a span with the name ended, i.e. for the untraced paths
+func (r *Recorder) RequireNoSpan(t testing.TB, name string) {
+	t.Helper()
+
+	for _, s := range r.Ended() {
+		if s.Name() == name {
+			t.Fatalf("unexpected span named %q, recorded:\n%s", name, r.describe())
+		}
+	}
+}
+
+// RequireAttribute fails the test unless the span has the attribute with the same value
+func RequireAttribute(t testing.TB, span sdktrace.ReadOnlySpan, want attribute.KeyValue) {
+	t.Helper()
+
+	for _, kv := range span.Attributes() {
+		if kv.Key != want.Key {
+			continue
+		}
+		if kv.Value != want.Value {
+			t.Fatalf("span %q has %s=%s, want %s", span.Name(), kv.Key, kv.Value.Emit(), want.Value.Emit())
+		}
+		return
+	}
+	t.Fatalf("span %q has no attribute %s, attributes: %s", span.Name(), want.Key, formatAttributes(span.Attributes()))
+}
+
+// RequireEvent returns the first event of the span with the name and the attributes,
+// failing the test if there is none
+func RequireEvent(t testing.TB, span sdktrace.ReadOnlySpan, name string, attrs ...attribute.KeyValue) sdktrace.Event {
+	t.Helper()
+
+	for _, event := range span.Events() {
+		if event.Name == name && hasAttributes(event.Attributes, attrs) {
+			return event
+		}
+	}
+
+	names := make([]string, len(span.Events()))
+	for i, event := range span.Events() {
+		names[i] = event.Name + " " + formatAttributes(event.Attributes)
+	}
+	t.Fatalf("span %q has no event %q with %s, events:\n%s", span.Name(), name, formatAttributes(attrs), strings.Join(names, "\n"))
+	return sdktrace.Event{}
+}
+
+// RequireStatus fails the test unless the span has the status code
+func RequireStatus(t testing.TB, span sdktrace.ReadOnlySpan, code codes.Code) {
+	t.Helper()
+
+	if span.Status().Code != code {
+		t.Fatalf("span %q has status %s %q, want %s", span.Name(), span.Status().Code, span.Status().Description, code)
+	}
+}
+
+// waitFor returns the spans matching, waiting for at least one to end
+func (r *Recorder) waitFor(match func(sdktrace.ReadOnlySpan) bool) []sdktrace.ReadOnlySpan {
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		var spans []sdktrace.ReadOnlySpan
+		for _, s := range r.Ended() {
+			if match(s) {
+				spans = append(spans, s)
+			}
+		}
+		if len(spans) > 0 || time.Now().After(deadline) {
+			return spans
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// requireOne waits for the span matching to end, failing the test if there is none or more than one
+func (r *Recorder) requireOne(t testing.TB, description string, match func(sdktrace.ReadOnlySpan) bool) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := r.waitFor(match)
+	if len(spans) != 1 {
+		t.Fatalf("%d %s, want 1, recorded:\n%s", len(spans), description, r.describe())
+	}
+	return spans[0]
+}
+
+// describe lists the spans recorded, with their parent, for the failure messages
+func (r *Recorder) describe() string {
+	spans := r.Ended()
+	if len(spans) == 0 {
+		return "  (none)"
+	}
+
+	names := make(map[string]string, len(spans))
+	for _, s := range spans {
+		names[s.SpanContext().SpanID().String()] = s.Name()
+	}
+
+	var b strings.Builder
+	for _, s := range spans {
+		parent := "root"
+		if s.Parent().IsValid() {
+			parent = names[s.Parent().SpanID().String()]
+			if parent == "" {
+				parent = "remote " + s.Parent().SpanID().String()
+			}
+		}
+		fmt.Fprintf(&b, "  %q (%s) under %s\n", s.Name(), s.SpanKind(), parent)
+	}
+	return b.String()
+}
+
+func hasAttributes(attrs, want []attribute.KeyValue) bool {
+	for _, w := range want {
+		found := false
+		for _, kv := range attrs {
+			if kv.Key == w.Key && kv.Value == w.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func formatAttributes(attrs []attribute.KeyValue) string {
+	parts := make([]string, len(attrs))
+	for i, kv := range attrs {
+		parts[i] = fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit())
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
@@ -0,0 +1,38 @@
+package telemetrytest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+)
+
+// NewFiberApp creates the app with the same middlewares of server.NewFiberApp, tracing with
+// the recording TracerProvider. The routes under test can then be added and called with Do.
+func NewFiberApp(t testing.TB, opts ...server.Option) *server.FiberApp {
+	t.Helper()
+
+	TracerProvider()
+
+	app, err := server.NewFiberApp(context.Background(), append(opts, server.WithoutTelemetrySetup())...)
+	if err != nil {
+		t.Fatalf("failed to create Fiber app: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = app.Shutdown()
+	})
+	return app
+}
+
+// Do sends the request to the app with app.Test, without a time limit, failing the test on error.
+// The body of the response must be closed.
+func Do(t testing.TB, app *server.FiberApp, req *http.Request) *http.Response {
+	t.Helper()
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", req.Method, req.URL, err)
+	}
+	return resp
+}
@@ -0,0 +1,55 @@
+package telemetrytest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	protos "github.com/emanuelef/go-fiber-honeycomb/proto"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Size of the in-memory buffer of the connections
+const bufSize = 1 << 20
+
+// StartGreeter serves the Greeter implementation over an in-memory connection, with the
+// stats handler and the interceptors of server.NewGRPCServer, and returns a client instrumented
+// with otelgrpc. The server is stopped at the end of the test.
+func StartGreeter(t testing.TB, greeter protos.GreeterServer, opts ...server.Option) protos.GreeterClient {
+	t.Helper()
+
+	TracerProvider()
+
+	srv, err := server.NewGRPCServer(context.Background(), append(opts, server.WithoutTelemetrySetup())...)
+	if err != nil {
+		t.Fatalf("failed to create gRPC server: %v", err)
+	}
+	protos.RegisterGreeterServer(srv, greeter)
+
+	lis := bufconn.Listen(bufSize)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		srv.Stop()
+		t.Fatalf("failed to create gRPC client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		srv.Stop()
+	})
+	return protos.NewGreeterClient(conn)
+}
@@ -0,0 +1,95 @@
+// Package telemetrytest records in memory the spans of the Fiber apps and gRPC servers of the
+// project, to assert in the tests on their names, parent/child structure, attributes and events.
+//
+// A single TracerProvider, sampling everything, is installed as the global one the first time
+// a Recorder is created, so that the tracers created in the init of a package, i.e. the one of
+// the main app, use it too. Each Recorder only sees the spans ended while its test runs: the
+// tests using it must not run in parallel.
+package telemetrytest
+
+import (
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	providerOnce sync.Once
+	provider     *sdktrace.TracerProvider
+)
+
+// TracerProvider returns the global provider the spans are recorded from, installing it with
+// the W3C trace context and baggage propagators the first time
+func TracerProvider() *sdktrace.TracerProvider {
+	providerOnce.Do(func() {
+		provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		))
+	})
+	return provider
+}
+
+// Recorder holds the spans ended since it was created, or since the last Reset
+type Recorder struct {
+	mu       sync.Mutex
+	recorder *tracetest.SpanRecorder
+}
+
+// NewRecorder starts recording the spans, until the end of the test
+func NewRecorder(t testing.TB) *Recorder {
+	t.Helper()
+
+	tp := TracerProvider()
+	r := &Recorder{recorder: tracetest.NewSpanRecorder()}
+	tp.RegisterSpanProcessor(r.recorder)
+	t.Cleanup(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		tp.UnregisterSpanProcessor(r.recorder)
+	})
+	return r
+}
+
+// Ended returns the spans ended so far, in the order they ended
+func (r *Recorder) Ended() []sdktrace.ReadOnlySpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.recorder.Ended()
+}
+
+// Reset forgets the spans recorded so far, i.e. between the steps of a test
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorder.Reset()
+}
+
+// Trace returns the spans of a trace, in the order they ended
+func (r *Recorder) Trace(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range r.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Children returns the spans whose parent is span
+func (r *Recorder) Children(span sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	var children []sdktrace.ReadOnlySpan
+	for _, s := range r.Ended() {
+		if s.Parent().SpanID() == span.SpanContext().SpanID() && s.Parent().TraceID() == span.SpanContext().TraceID() {
+			children = append(children, s)
+		}
+	}
+	return children
+}
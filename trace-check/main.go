@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/config"
+	"github.com/emanuelef/go-fiber-honeycomb/fakeupstream"
+	"github.com/emanuelef/go-fiber-honeycomb/proto"
+	"github.com/emanuelef/go-fiber-honeycomb/server"
+	"github.com/emanuelef/go-fiber-honeycomb/tracecheck"
+	"github.com/emanuelef/go-fiber-honeycomb/traceparent"
+)
+
+// endpoint is a path of the main app with the spans expected in its trace
+type endpoint struct {
+	path string
+	want tracecheck.Span
+}
+
+// call is a request made to an endpoint, in the trace started with the traceparent sent
+type call struct {
+	endpoint
+	traceID      string
+	parentSpanID string
+	err          error
+}
+
+func main() {
+	ctx, stop := server.SignalContext(context.Background())
+	defer stop()
+
+	cfg, err := config.Load(config.WithSections(config.TraceCheckSection))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the paths passed as arguments, i.e. /hello-grpc, select the endpoints checked
+	endpoints := expectedTraces(&cfg.TraceCheck)
+	if paths := os.Args[1:]; len(paths) > 0 {
+		endpoints = slices.DeleteFunc(endpoints, func(e endpoint) bool {
+			return !slices.Contains(paths, strings.SplitN(e.path, "?", 2)[0])
+		})
+		if len(endpoints) == 0 {
+			log.Fatalf("no endpoint matches %v", paths)
+		}
+	}
+
+	httpClient := &http.Client{Timeout: cfg.TraceCheck.Timeout}
+	receiver := tracecheck.NewClient(cfg.TraceCheck.ReceiverURL, httpClient)
+
+	// all the endpoints are called before waiting for the traces, exported in batches
+	calls := make([]call, len(endpoints))
+	for i, e := range endpoints {
+		calls[i] = callEndpoint(ctx, httpClient, cfg.TraceCheck.MainURL, e)
+	}
+
+	failed := 0
+	for _, c := range calls {
+		if !checkTrace(ctx, receiver, c, cfg.TraceCheck.Timeout) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d of %d traces failed\n", failed, len(calls))
+		os.Exit(1)
+	}
+	fmt.Printf("\nall %d traces passed\n", len(calls))
+}
+
+// callEndpoint calls the main app with a new traceparent
+func callEndpoint(ctx context.Context, httpClient *http.Client, mainURL string, e endpoint) call {
+	sc := traceparent.NewSpanContext()
+	c := call{endpoint: e, traceID: sc.TraceID().String(), parentSpanID: sc.SpanID().String()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(mainURL, "/")+e.path, nil)
+	if err != nil {
+		c.err = fmt.Errorf("failed to create request: %w", err)
+		return c
+	}
+	traceparent.Inject(req, sc)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		c.err = fmt.Errorf("failed to call %s: %w", e.path, err)
+		return c
+	}
+	// the streams end with the body
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		c.err = fmt.Errorf("%s returned %s", e.path, resp.Status)
+	}
+	return c
+}
+
+// checkTrace waits for the trace of the call and prints the outcome, with the diff when it doesn't match
+func checkTrace(ctx context.Context, receiver *tracecheck.Client, c call, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := receiver.Wait(ctx, c.traceID, c.parentSpanID, c.want)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", c.path, err)
+		return false
+	}
+
+	ok := result.OK && c.err == nil
+	outcome := "PASS"
+	if !ok {
+		outcome = "FAIL"
+	}
+	fmt.Printf("%s %s trace %s, %d spans in %s\n", outcome, c.path, c.traceID, result.SpanCount, time.Since(start).Round(time.Millisecond))
+
+	if c.err != nil {
+		fmt.Printf("  %v\n", c.err)
+	}
+	if !result.OK {
+		fmt.Printf("  %s\n", receiver.TraceURL(c.traceID))
+		fmt.Printf("  spans expected (-) missing and received (+) instead:\n")
+		for _, line := range strings.Split(strings.TrimSuffix(result.Diff, "\n"), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	return ok
+}
+
+// expectedTraces returns the endpoints of the main app, with the topology of their traces
+func expectedTraces(cfg *config.TraceCheck) []endpoint {
+	mainService, secondaryService, grpcService := cfg.MainService, cfg.SecondaryService, cfg.GRPCService
+
+	// the request to EXTERNAL_URL, served by the fake-upstream app when it's traced
+	external := func(service string) tracecheck.Span {
+		if cfg.UpstreamService == "" {
+			return tracecheck.ClientSpan(service, "HTTP GET")
+		}
+		return tracecheck.ClientSpan(service, "HTTP GET",
+			tracecheck.ServerSpan(cfg.UpstreamService, fakeupstream.PokemonPath),
+		)
+	}
+
+	// the request to /hello of the secondary app, that calls EXTERNAL_URL too
+	secondaryHello := tracecheck.ClientSpan(mainService, "HTTP GET",
+		tracecheck.ServerSpan(secondaryService, "/hello",
+			external(secondaryService),
+			tracecheck.InternalSpan(secondaryService, "custom-span-secondary", external(secondaryService)),
+		),
+	)
+
+	// the RPCs to the gRPC server, i.e. protos.Greeter/SayHello
+	rpc := func(fullMethod string, children ...tracecheck.Span) tracecheck.Span {
+		method := strings.TrimPrefix(fullMethod, "/")
+		return tracecheck.ClientSpan(mainService, method, tracecheck.ServerSpan(grpcService, method, children...))
+	}
+
+	return []endpoint{
+		{"/hello", tracecheck.ServerSpan(mainService, "/hello")},
+		{"/hello-child", tracecheck.ServerSpan(mainService, "/hello-child",
+			tracecheck.InternalSpan(mainService, "custom-child-span"),
+		)},
+		{"/hello-otelhttp", tracecheck.ServerSpan(mainService, "/hello-otelhttp",
+			external(mainService),
+			secondaryHello,
+			tracecheck.InternalSpan(mainService, "custom-span",
+				external(mainService),
+				tracecheck.InternalSpan(mainService, "child-operation"),
+			),
+		)},
+		{"/hello-http-client", tracecheck.ServerSpan(mainService, "/hello-http-client",
+			external(mainService),
+			secondaryHello,
+		)},
+		{"/hello-resty", tracecheck.ServerSpan(mainService, "/hello-resty",
+			external(mainService),
+			external(mainService),
+			secondaryHello,
+		)},
+		{"/hello-grpc", tracecheck.ServerSpan(mainService, "/hello-grpc",
+			rpc(protos.Greeter_SayHello_FullMethodName, tracecheck.InternalSpan(grpcService, "SayHelloCustom")),
+		)},
+		{"/hello-grpc-server-stream?count=3&interval_ms=10", tracecheck.ServerSpan(mainService, "/hello-grpc-server-stream",
+			rpc(protos.Greeter_SayHelloServerStream_FullMethodName),
+		)},
+		{"/hello-grpc-client-stream?count=3", tracecheck.ServerSpan(mainService, "/hello-grpc-client-stream",
+			rpc(protos.Greeter_SayHelloClientStream_FullMethodName),
+		)},
+		{"/hello-grpc-bidi-stream?count=3", tracecheck.ServerSpan(mainService, "/hello-grpc-bidi-stream",
+			rpc(protos.Greeter_SayHelloBidiStream_FullMethodName),
+		)},
+	}
+}
@@ -0,0 +1,93 @@
+package tracecheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emanuelef/go-fiber-honeycomb/otlpreceiver"
+)
+
+// Time between the requests to the receiver while waiting for the spans of a trace
+const pollInterval = 250 * time.Millisecond
+
+// Client fetches the traces from the JSON API of the otlp-receiver app
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient calls the receiver at baseURL, i.e. http://localhost:4318
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// TraceURL is the page of the trace in the viewer of the receiver
+func (c *Client) TraceURL(traceID string) string {
+	return c.baseURL + "/traces/" + traceID
+}
+
+// Trace returns the spans of a trace, otlpreceiver.ErrTraceNotFound when none was received yet
+func (c *Client) Trace(ctx context.Context, traceID string) ([]otlpreceiver.Span, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/traces/"+url.PathEscape(traceID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trace: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, otlpreceiver.ErrTraceNotFound
+	default:
+		return nil, fmt.Errorf("failed to get trace: unexpected status %s", resp.Status)
+	}
+
+	var t otlpreceiver.Trace
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to decode trace: %w", err)
+	}
+	return t.Spans, nil
+}
+
+// Wait polls the trace until it matches want or the context is done, returning the last result:
+// the spans are exported in batches, so the ones of the different services arrive at different times
+func (c *Client) Wait(ctx context.Context, traceID, parentSpanID string, want Span) (Result, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	last := Check(nil, parentSpanID, want)
+	for {
+		spans, err := c.Trace(ctx, traceID)
+		switch {
+		case err == nil:
+			last = Check(spans, parentSpanID, want)
+			if last.OK {
+				return last, nil
+			}
+		case ctx.Err() != nil:
+			return last, nil
+		case !errors.Is(err, otlpreceiver.ErrTraceNotFound):
+			return last, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, nil
+		case <-ticker.C:
+		}
+	}
+}
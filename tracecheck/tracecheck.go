@@ -0,0 +1,170 @@
+// Package tracecheck verifies the topology of the distributed traces received by the otlp-receiver
+// app: a trace, started with a traceparent sent by the caller, must contain a tree of expected spans,
+// each one a direct child of the previous one, so that a broken propagation is detected.
+// The spans not expected, i.e. the ones of httptrace, are ignored.
+package tracecheck
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emanuelef/go-fiber-honeycomb/otlpreceiver"
+)
+
+// Span is an expected span, matched by service, kind and name, the empty fields match any value
+type Span struct {
+	Service string
+	Kind    string
+	Name    string
+	// Children must be direct children of the span, in any order
+	Children []Span
+}
+
+// ServerSpan expects a server span of the service, i.e. the route of a Fiber app or a gRPC method
+func ServerSpan(service, name string, children ...Span) Span {
+	return Span{Service: service, Kind: "server", Name: name, Children: children}
+}
+
+// ClientSpan expects a client span of the service, i.e. an HTTP request or an RPC
+func ClientSpan(service, name string, children ...Span) Span {
+	return Span{Service: service, Kind: "client", Name: name, Children: children}
+}
+
+// InternalSpan expects a span created by the code of the service
+func InternalSpan(service, name string, children ...Span) Span {
+	return Span{Service: service, Kind: "internal", Name: name, Children: children}
+}
+
+func (s Span) String() string {
+	return describe(s.Service, s.Kind, s.Name)
+}
+
+// size is the number of spans of the tree
+func (s Span) size() int {
+	n := 1
+	for _, child := range s.Children {
+		n += child.size()
+	}
+	return n
+}
+
+func (s Span) matches(span *otlpreceiver.Span) bool {
+	return (s.Service == "" || s.Service == span.Service) &&
+		(s.Kind == "" || s.Kind == span.Kind) &&
+		(s.Name == "" || s.Name == span.Name)
+}
+
+func describe(service, kind, name string) string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{service, kind, name} {
+		if p == "" {
+			p = "*"
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Result of a Check
+type Result struct {
+	OK bool
+	// Diff is the tree of the expected spans, the missing ones prefixed by "-" and, at the same
+	// level, the spans received instead prefixed by "+"
+	Diff string
+	// SpanCount is the number of spans of the trace received
+	SpanCount int
+}
+
+// Check compares the spans of a trace with the expected tree, rooted at the child of the span
+// parentSpanID, the one of the traceparent sent, or at a span without parent when empty
+func Check(spans []otlpreceiver.Span, parentSpanID string, want Span) Result {
+	c := &checker{children: map[string][]*otlpreceiver.Span{}}
+	for i := range spans {
+		s := &spans[i]
+		c.children[s.ParentSpanID] = append(c.children[s.ParentSpanID], s)
+	}
+
+	var b strings.Builder
+	matched := c.match(&b, []Span{want}, c.children[parentSpanID], 0)
+	return Result{OK: matched == want.size(), Diff: b.String(), SpanCount: len(spans)}
+}
+
+type checker struct {
+	// spans by the ID of their parent
+	children map[string][]*otlpreceiver.Span
+}
+
+// match finds each expected span among the spans received at the same level, writing the diff to w,
+// and returns how many of the expected spans, with their descendants, were found
+func (c *checker) match(w io.Writer, want []Span, spans []*otlpreceiver.Span, depth int) int {
+	// pairs first the expected spans and the spans received with the most descendants matching,
+	// so that i.e. an HTTP request expected to reach the secondary app doesn't take the span of
+	// another request when the secondary app is missing
+	scores := make([][]int, len(want))
+	for i, expected := range want {
+		scores[i] = make([]int, len(spans))
+		for j, s := range spans {
+			scores[i][j] = -1
+			if expected.matches(s) {
+				scores[i][j] = c.match(io.Discard, expected.Children, c.children[s.SpanID], 0)
+			}
+		}
+	}
+
+	found := make([]int, len(want))
+	for i := range found {
+		found[i] = -1
+	}
+	used := make([]bool, len(spans))
+	for {
+		bi, bj := -1, -1
+		for i := range want {
+			for j := range spans {
+				if found[i] >= 0 || used[j] || scores[i][j] < 0 {
+					continue
+				}
+				if bi < 0 || scores[i][j] > scores[bi][bj] {
+					bi, bj = i, j
+				}
+			}
+		}
+		if bi < 0 {
+			break
+		}
+		found[bi], used[bj] = bj, true
+	}
+
+	matched, missing := 0, false
+	for i, expected := range want {
+		if found[i] < 0 {
+			missing = true
+			writeMissing(w, expected, depth)
+			continue
+		}
+
+		fmt.Fprintf(w, "  %s%s\n", indent(depth), expected)
+		matched += 1 + c.match(w, expected.Children, c.children[spans[found[i]].SpanID], depth+1)
+	}
+
+	// shows what was received in place of the missing spans
+	if missing {
+		for i, s := range spans {
+			if !used[i] {
+				fmt.Fprintf(w, "+ %s%s\n", indent(depth), describe(s.Service, s.Kind, s.Name))
+			}
+		}
+	}
+	return matched
+}
+
+func writeMissing(w io.Writer, s Span, depth int) {
+	fmt.Fprintf(w, "- %s%s\n", indent(depth), s)
+	for _, child := range s.Children {
+		writeMissing(w, child, depth+1)
+	}
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
@@ -0,0 +1,34 @@
+// Package traceparent starts traces with a known ID from outside of the apps: the caller creates
+// a span context and sends it with the traceparent header, the app continues the trace from it.
+// It is used by the trace-check and load-test commands to find the traces of their requests.
+package traceparent
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewSpanContext returns a sampled remote span context with random IDs, to start a trace
+// with a known ID sending it as traceparent with Inject
+func NewSpanContext() trace.SpanContext {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	_, _ = rand.Read(traceID[:])
+	_, _ = rand.Read(spanID[:])
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// Inject sets the traceparent header of the request
+func Inject(req *http.Request, sc trace.SpanContext) {
+	ctx := trace.ContextWithRemoteSpanContext(req.Context(), sc)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+}